@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Function:     answersDir
+//
+// Description:  This function gives the directory the remembered answers are kept in.
+func answersDir() string {
+	return configPath("answers")
+}
+
+// Function:     answersFile
+//
+// Description:  This function gives the file the remembered answers of a template are
+//
+//	kept in.
+//
+// Inputs:
+//
+//	template    The name of the template
+func answersFile(template string) string {
+	return filepath.Join(answersDir(), fmt.Sprintf("%s.json", filepath.Base(template)))
+}
+
+// Function:     loadAnswers
+//
+// Description:  This function reads the last answers given for a template. It gives an
+//
+//	empty map if there aren't any.
+//
+// Inputs:
+//
+//	template    The name of the template
+func loadAnswers(template string) map[string]interface{} {
+	answers := make(map[string]interface{})
+	if template == "" {
+		return answers
+	}
+	data, err := os.ReadFile(answersFile(template))
+	if err != nil {
+		return answers
+	}
+	_ = json.Unmarshal(data, &answers)
+	return answers
+}
+
+// Function:     saveAnswers
+//
+// Description:  This function keeps the answers given for a template. Passwords are
+//
+//	never kept.
+//
+// Inputs:
+//
+//	template    The name of the template
+//	items       The items of the dialog
+//	values      The values keyed by the item name
+func saveAnswers(template string, items []DialogItem, values map[string]interface{}) error {
+	if template == "" {
+		return nil
+	}
+	answers := loadAnswers(template)
+	for key, val := range values {
+		answers[key] = val
+	}
+	for _, item := range items {
+		if item.ModelType == "password" {
+			delete(answers, item.Name)
+		}
+	}
+	if err := os.MkdirAll(answersDir(), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(answers, "", " ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(answersFile(template), data, 0600)
+}
+
+// Function:     clearAnswers
+//
+// Description:  This function forgets the answers given for a template.
+//
+// Inputs:
+//
+//	template    The name of the template
+func clearAnswers(template string) error {
+	err := os.Remove(answersFile(template))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("there aren't any answers kept for %s", template)
+	}
+	return err
+}
+
+// Function:     prefillItems
+//
+// Description:  This function sets the values of the items to the answers given. The
+//
+//	radio button with the value given is checked. Options and passwords are
+//	left as they are.
+//
+// Inputs:
+//
+//	items       The items to fill in
+//	answers     The answers keyed by the item name
+func prefillItems(items []DialogItem, answers map[string]interface{}) []DialogItem {
+	filled := append([]DialogItem(nil), items...)
+	for i, item := range filled {
+		if item.HtmlType == "" || item.ModelType == "password" {
+			continue
+		}
+		if val, ok := answers[item.Name]; ok && val != nil {
+			filled[i].fill(val)
+		}
+	}
+	return filled
+}
+
+// Function:     fill
+//
+// Description:  This method sets an item to an answer. A radio button keeps its value
+//
+//	and is checked if the answer is its value.
+//
+// Inputs:
+//
+//	val         The answer for the item
+func (item *DialogItem) fill(val interface{}) {
+	if item.ModelType == "radio" {
+		item.Checked = fmt.Sprint(val) == item.Value
+		return
+	}
+	item.Value = fmt.Sprint(val)
+}
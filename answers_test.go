@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+// The radio button with the remembered value is checked and the others aren't.
+func TestPrefillRadios(t *testing.T) {
+	items := []DialogItem{
+		{ModelType: "radio", HtmlType: "radio", Name: "size", Id: "small", Value: "small", Checked: true},
+		{ModelType: "radio", HtmlType: "radio", Name: "size", Id: "large", Value: "large"},
+		{ModelType: "input", HtmlType: "text", Name: "title", Id: "title"},
+		{ModelType: "password", HtmlType: "password", Name: "secret", Id: "secret"},
+	}
+	answers := map[string]interface{}{"size": "large", "title": "Report", "secret": "hunter2"}
+
+	filled := prefillItems(items, answers)
+	if filled[0].Checked || !filled[1].Checked {
+		t.Fatalf("the radios are %+v and %+v", filled[0], filled[1])
+	}
+	if filled[0].Value != "small" || filled[1].Value != "large" {
+		t.Fatalf("the radio values changed to %q and %q", filled[0].Value, filled[1].Value)
+	}
+	if filled[2].Value != "Report" || filled[3].Value != "" {
+		t.Fatalf("the inputs are %+v and %+v", filled[2], filled[3])
+	}
+	if !items[0].Checked {
+		t.Fatal("the items given were changed")
+	}
+}
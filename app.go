@@ -2,10 +2,10 @@ package main
 
 import (
 	"context"
+	"log"
 	"net/http"
-	"net/url"
 	"os"
-	"time"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	rt "github.com/wailsapp/wails/v2/pkg/runtime"
@@ -13,13 +13,17 @@ import (
 
 // App struct
 type App struct {
-	ctx context.Context
-	srv *http.Server
+	ctx        context.Context
+	srv        *http.Server
+	messageSeq uint64 // Counts the messages shown so a ttl only hides its own message
+	messages   *messageQueue
+	progress   *progressBars
+	token      string // Callers that give it can send raw dialogs with scripts
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	return &App{}
+	return &App{messages: newMessageQueue(), progress: newProgressBars()}
 }
 
 func (a *App) domReady(ctx context.Context) {
@@ -34,41 +38,167 @@ func (a *App) shutdown(ctx context.Context) {
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 
+	//
+	// Get the token for trusted callers. Without it, no caller is trusted.
+	//
+	token, err := loadTrustToken()
+	if err != nil {
+		log.Printf("Unable to make the trust token: %v", err)
+	}
+	a.token = token
+
 	//
 	// We need to start the backend and setup the signaling.
 	//
 	go backend(a, ctx)
+	go a.runMessages(ctx)
+}
+
+// ChooseFile lets the user pick a file for a file item in a modal dialog. The
+// webview doesn't give the real path from a file input, so it is done here.
+func (a *App) ChooseFile(title string) (string, error) {
+	return rt.OpenFileDialog(a.ctx, rt.OpenDialogOptions{
+		Title: title,
+	})
 }
 
 type Msg struct {
 	Message string `json:"msg" xml:"user"  binding:"required"`
+	Title   string `json:"title"`
+	Level   string `json:"level"`
+	TTL     int    `json:"ttl"`
+	Format  string `json:"format"`
 }
 
 type Dialog struct {
-	Html   string `json:"html" binding:"required"`
-	Width  int    `json:"width" binding:"required"`
-	Height int    `json:"height" binding:"required"`
-	X      int    `json:"x" binding:"required"`
-	Y      int    `json:"y" binding:"required"`
+	Html    string `json:"html" binding:"required"`
+	Width   int    `json:"width" binding:"required"`
+	Height  int    `json:"height" binding:"required"`
+	X       int    `json:"x" binding:"required"`
+	Y       int    `json:"y" binding:"required"`
+	Timeout int    `json:"timeout,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Trusted bool   `json:"trusted"`
 }
 
 type DialogItem struct {
-	ModelType string `json:"modaltype" binding:"required"`
-	Name      string `json:"name" binding:"required"`
-	Id        string `json:"id" binding:"required"`
-	Value     string `json:"value" binding:"required"`
-	For       string `json:"for" binding:"required"`
+	ModelType    string         `json:"modaltype" binding:"required"`
+	Name         string         `json:"name" binding:"required"`
+	Id           string         `json:"id" binding:"required"`
+	Value        string         `json:"value"`
+	Checked      bool           `json:"checked,omitempty"`
+	For          string         `json:"for"`
+	HtmlType     string         `json:"htmltype,omitempty"`
+	Required     bool           `json:"required,omitempty"`
+	Pattern      string         `json:"pattern,omitempty"`
+	Min          string         `json:"min,omitempty"`
+	Max          string         `json:"max,omitempty"`
+	MinLength    int            `json:"minLength,omitempty"`
+	MaxLength    int            `json:"maxLength,omitempty"`
+	ErrorMessage string         `json:"errorMessage,omitempty"`
+	ShowIf       *Condition     `json:"showIf,omitempty"`
+	OptionsFrom  *OptionsSource `json:"optionsFrom,omitempty"`
 }
 
 type DialogButton struct {
 	Name   string `json:"name" binding:"required"`
 	Id     string `json:"id" binding:"required"`
 	Action string `json:"action" binding:"required"`
+	Submit *bool  `json:"submit,omitempty"`
+}
+
+// submitsValues tells if pressing the button returns the values of the dialog.
+// Only the submit and wizard next actions do unless the button says otherwise.
+func (b DialogButton) submitsValues() bool {
+	if b.Submit != nil {
+		return *b.Submit
+	}
+	return b.Action == "submit" || b.Action == "next"
+}
+
+// button finds the button with the given id. The Enter key doesn't come from a
+// button, so an unknown id is taken as a submit.
+func (d ModalDialog) button(id string) DialogButton {
+	for _, b := range d.Buttons {
+		if b.Id == id {
+			return b
+		}
+	}
+	return DialogButton{Id: id, Action: "submit"}
 }
 
 type ModalDialog struct {
-	Items   []DialogItem   `json:"items" binding:"required"`
-	Buttons []DialogButton `json:"buttons" binding:"required"`
+	Items    []DialogItem   `json:"items" binding:"required"`
+	Buttons  []DialogButton `json:"buttons" binding:"required"`
+	Timeout  int            `json:"timeout,omitempty"`
+	Step     *WizardStep    `json:"step,omitempty"`
+	Name     string         `json:"name,omitempty"`
+	Remember bool           `json:"remember,omitempty"`
+}
+
+// Function:     runModal
+//
+// Description:  This function sends a modal dialog to the frontend and waits for the
+//
+//	user to answer it. The values are checked again here since the frontend
+//	can't be trusted to have done it. Bad values send the dialog back to the user.
+//
+// Inputs:
+//
+//	ctx        The Wails runtime context
+//	done       Closed when the caller has gone away
+//	dialog     The modal dialog to show
+func runModal(ctx context.Context, done <-chan struct{}, dialog ModalDialog) DialogResult {
+	//
+	// Send it to the frontend.
+	//
+	returned, seq := showDialog(ctx, "modal", dialog)
+
+	for {
+		optionalData, status := waitForReturn(ctx, done, returned, seq, dialog.Timeout)
+		if status != StatusSubmitted {
+			return DialogResult{Status: status, Values: map[string]interface{}{}}
+		}
+
+		//
+		// What happens depends on the button the user pressed.
+		//
+		id, values := parseModalReturn(optionalData)
+		button := dialog.button(id)
+		result := DialogResult{Status: StatusSubmitted, Button: id, Action: button.Action, Values: map[string]interface{}{}}
+		if button.Action == "cancel" {
+			result.Status = StatusCanceled
+			return result
+		}
+		if !button.submitsValues() {
+			return result
+		}
+		//
+		// Hidden items aren't checked or returned.
+		//
+		problems := validateAnswers(dialog, values)
+		if len(problems) == 0 {
+			result.Values = typedValues(dialog, dialog.visibleValues(values))
+			return result
+		}
+		returned = listenForReturn(ctx)
+		rt.EventsEmit(ctx, "modalerrors", problems)
+	}
+}
+
+// Function:     badDialogRequest
+//
+// Description:  This function answers a dialog request that can't be shown and keeps
+//
+//	the reason for the audit log.
+//
+// Inputs:
+//
+//	c          The gin context of the request
+//	err        What is wrong with the request
+func badDialogRequest(c *gin.Context, err error) {
+	c.Set(auditErrorKey, err.Error())
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 }
 
 func backend(a *App, ctx context.Context) {
@@ -79,120 +209,221 @@ func backend(a *App, ctx context.Context) {
 	r.Use(gin.Recovery())
 
 	//
-	// Define the message route. The message is given on the URI string and in the body.
+	// Define the message routes. The message is given in the body as json or plain
+	// text, so it can be as long as needed.
 	//
-	r.GET("/api/message/:message", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"msg": "okay",
-		})
-		var json Msg
-		if err := c.ShouldBindJSON(&json); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	postMessage := func(c *gin.Context) {
+		msg, status, err := readMessage(c)
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
 			return
 		}
-		message := c.Param("message")
-		messageBody := json.Message
-		if messageBody != message {
-			message = messageBody
-		}
 
-		message, err := url.QueryUnescape(message)
+		//
+		// Queue it for the frontend.
+		//
+		a.messages.push(msg)
+		c.JSON(http.StatusAccepted, gin.H{"msg": "okay"})
+	}
+	r.POST("/api/message", postMessage)
+	r.PUT("/api/message", postMessage)
+
+	//
+	// Define the append to message routes.
+	//
+	postAppend := func(c *gin.Context) {
+		msg, status, err := readMessage(c)
 		if err != nil {
-			// An error in decoding.
-			message = ""
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
 		}
 
 		//
-		// Send it to the frontend.
+		// Queue it behind the messages already waiting.
 		//
-		rt.EventsEmit(ctx, "message", message)
-	})
+		a.messages.pushAppend(msg.Message)
+		c.JSON(http.StatusAccepted, gin.H{"msg": "okay"})
+	}
+	r.POST("/api/message/append", postAppend)
+	r.PUT("/api/message/append", postAppend)
 
 	//
-	// Define the append to message route. This one is URI only.
+	// The older message routes give the message on the URI string. A json body can
+	// still give the title, level, and ttl, or a message in place of the one on the URI.
 	//
-	r.GET("/api/message/append/:message", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"msg": "okay",
-		})
-		var json Msg
-		if err := c.ShouldBindJSON(&json); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	r.GET("/api/message/:message", func(c *gin.Context) {
+		msg, status, err := readUriMessage(c)
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
 			return
 		}
-		message := c.Param("message")
-		messageBody := json.Message
-		if messageBody != message {
-			message = messageBody
+		a.messages.push(msg)
+		c.JSON(http.StatusOK, gin.H{"msg": "okay"})
+	})
+	r.GET("/api/message/append/:message", func(c *gin.Context) {
+		msg, status, err := readUriMessage(c)
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
 		}
+		a.messages.pushAppend(msg.Message)
+		c.JSON(http.StatusOK, gin.H{"msg": "okay"})
+	})
 
-		message, err := url.QueryUnescape(message)
+	//
+	// Define the message history route. A limit gives only that many of the newest.
+	//
+	r.GET("/api/messages", func(c *gin.Context) {
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
 		if err != nil {
-			// An error in decoding.
-			message = ""
+			c.JSON(http.StatusBadRequest, gin.H{"error": "the limit has to be a number"})
+			return
 		}
+		c.JSON(http.StatusOK, gin.H{"messages": a.messages.recent(limit)})
+	})
 
-		//
-		// Send it to the frontend.
-		//
-		rt.EventsEmit(ctx, "append", message)
+	//
+	// Define the progress bar routes. A bar is started, updated any number of times,
+	// and then deleted when the work is done.
+	//
+	r.POST("/api/progress", func(c *gin.Context) {
+		var json ProgressStart
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		bar, err := a.progress.start(ctx, json)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, bar)
+	})
+	r.PATCH("/api/progress/:id", func(c *gin.Context) {
+		var json ProgressUpdate
+		if err := c.ShouldBindJSON(&json); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		bar, ok := a.progress.update(ctx, c.Param("id"), json)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "there isn't a progress bar with that id"})
+			return
+		}
+		c.JSON(http.StatusOK, bar)
+	})
+	r.DELETE("/api/progress/:id", func(c *gin.Context) {
+		if !a.progress.done(ctx, c.Param("id")) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "there isn't a progress bar with that id"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"msg": "okay"})
 	})
 
 	//
 	// Add the dialog route for user defined raw dialogs.
 	//
-	r.PUT("/api/dialog", func(c *gin.Context) {
+	r.PUT("/api/dialog", auditLog(), func(c *gin.Context) {
 		var json Dialog
 		if err := c.ShouldBindJSON(&json); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			badDialogRequest(c, err)
 			return
 		}
 
+		c.Set(auditTemplateKey, json.Name)
+
+		//
+		// Only trusted callers can run scripts in the dialog. The html of everyone
+		// else is cleaned of scripts and event handlers.
+		//
+		json.Trusted = a.isTrusted(c.GetHeader(trustHeader))
+		if !json.Trusted {
+			json.Html = sanitizeRawHtml(json.Html)
+		}
+
 		//
 		// Send it to the frontend.
 		//
-		rt.EventsEmit(ctx, "dialog", json)
+		returned, seq := showDialog(ctx, "dialog", json)
 
 		//
 		// Get the return.
 		//
-		running := true
-		rt.EventsOn(ctx, "dialogreturn", func(optionalData ...interface{}) {
-			c.JSON(http.StatusOK, optionalData)
-			running = false
-			rt.EventsOff(ctx, "dialogreturn")
-		})
-		for running {
-			time.Sleep(time.Millisecond)
+		result := DialogResult{Values: map[string]interface{}{}}
+		optionalData, status := waitForReturn(ctx, c.Request.Context().Done(), returned, seq, json.Timeout)
+		result.Status = status
+		if status == StatusSubmitted {
+			result = rawResult(optionalData)
+			c.Set(auditItemsKey, rawPasswordItems(optionalData))
 		}
+		c.Set(auditResultKey, result)
+		c.JSON(http.StatusOK, result)
 	})
 
 	//
 	// Add the dialog route for user defined raw dialogs.
 	//
-	r.PUT("/api/modal", func(c *gin.Context) {
+	r.PUT("/api/modal", auditLog(), func(c *gin.Context) {
 		var json ModalDialog
 		if err := c.ShouldBindJSON(&json); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			badDialogRequest(c, err)
 			return
 		}
 
 		//
-		// Send it to the frontend.
+		// Make sure every item is a type the frontend knows how to show.
 		//
-		rt.EventsEmit(ctx, "modal", json)
+		if err := normalizeModalDialog(&json); err != nil {
+			badDialogRequest(c, err)
+			return
+		}
+
+		c.Set(auditTemplateKey, json.Name)
+		c.Set(auditItemsKey, json.Items)
 
 		//
-		// Get the return.
+		// Fill in the last answers if the template asks for it.
 		//
-		running := true
-		rt.EventsOnce(ctx, "dialogreturn", func(optionalData ...interface{}) {
-			c.JSON(http.StatusOK, optionalData)
-			running = false
-		})
-		for running {
-			time.Sleep(time.Millisecond)
+		if json.Remember {
+			json.Items = prefillItems(json.Items, loadAnswers(json.Name))
+		}
+
+		//
+		// Show it and get the return.
+		//
+		result := runModal(ctx, c.Request.Context().Done(), json)
+		if json.Remember && result.Status == StatusSubmitted && len(result.Values) > 0 {
+			if err := saveAnswers(json.Name, json.Items, result.Values); err != nil {
+				log.Printf("Unable to keep the answers for %s: %v", json.Name, err)
+			}
+		}
+		c.Set(auditResultKey, result)
+		c.JSON(http.StatusOK, result)
+	})
+
+	//
+	// Add the wizard route for dialogs with several pages.
+	//
+	r.PUT("/api/wizard", auditLog(), func(c *gin.Context) {
+		var json Wizard
+		if err := c.ShouldBindJSON(&json); err != nil {
+			badDialogRequest(c, err)
+			return
+		}
+		if err := normalizeWizard(&json); err != nil {
+			badDialogRequest(c, err)
+			return
 		}
+
+		c.Set(auditTemplateKey, json.Name)
+		c.Set(auditItemsKey, json.allItems().Items)
+
+		//
+		// Show the pages and get the values from all of them.
+		//
+		result := runWizard(ctx, c.Request.Context().Done(), json)
+		c.Set(auditResultKey, result)
+		c.JSON(http.StatusOK, result)
 	})
 
 	//
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Struct:       AuditEntry
+//
+// Description:  This is one line of the audit log. There is one for each dialog asked.
+type AuditEntry struct {
+	Time       time.Time              `json:"time"`
+	RequestId  string                 `json:"requestId"`
+	Endpoint   string                 `json:"endpoint"`
+	Template   string                 `json:"template,omitempty"`
+	Caller     string                 `json:"caller"`
+	Outcome    string                 `json:"outcome"`
+	Error      string                 `json:"error,omitempty"`
+	DurationMs int64                  `json:"durationMs"`
+	Answers    map[string]interface{} `json:"answers,omitempty"`
+}
+
+// The keys for the audit information a handler gives the auditLog middleware.
+const (
+	auditTemplateKey = "auditTemplate"
+	auditResultKey   = "auditResult"
+	auditItemsKey    = "auditItems"
+	auditErrorKey    = "auditError"
+)
+
+// Keeps two requests from writing a line at the same time.
+var auditLock sync.Mutex
+
+// Function:     auditFile
+//
+// Description:  This function gives the file the audit log is kept in.
+func auditFile() string {
+	return configPath("audit.jsonl")
+}
+
+// Function:     newRequestId
+//
+// Description:  This function makes an id for a request that didn't give one.
+func newRequestId() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// Function:     redactAnswers
+//
+// Description:  This function gets the answers ready for the log. BB_LOG_ANSWERS set to
+//
+//	"none" leaves them out and "all" keeps passwords. Otherwise, the values of
+//	password items are hidden.
+//
+// Inputs:
+//
+//	items      The items of the dialog if it was a modal dialog
+//	values     The answers keyed by the item name
+func redactAnswers(items []DialogItem, values map[string]interface{}) map[string]interface{} {
+	mode := os.Getenv("BB_LOG_ANSWERS")
+	if mode == "none" || len(values) == 0 {
+		return nil
+	}
+	answers := make(map[string]interface{}, len(values))
+	for key, val := range values {
+		answers[key] = val
+	}
+	if mode != "all" {
+		for _, item := range items {
+			if item.ModelType == "password" {
+				if _, ok := answers[item.Name]; ok {
+					answers[item.Name] = "********"
+				}
+			}
+		}
+	}
+	return answers
+}
+
+// Function:     auditLog
+//
+// Description:  This function gives the middleware that adds a line to the audit log
+//
+//	for each dialog. The request id is taken from the X-Request-ID header or
+//	made up, and given back in the same header.
+func auditLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestId := c.GetHeader("X-Request-ID")
+		if requestId == "" {
+			requestId = newRequestId()
+		}
+		c.Header("X-Request-ID", requestId)
+
+		c.Next()
+
+		entry := AuditEntry{
+			Time:       start,
+			RequestId:  requestId,
+			Endpoint:   c.Request.Method + " " + c.FullPath(),
+			Template:   c.GetString(auditTemplateKey),
+			Caller:     c.ClientIP(),
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if result, ok := c.Get(auditResultKey); ok {
+			res := result.(DialogResult)
+			entry.Outcome = res.Status
+			var items []DialogItem
+			if list, ok := c.Get(auditItemsKey); ok {
+				items = list.([]DialogItem)
+			}
+			entry.Answers = redactAnswers(items, res.Values)
+		} else {
+			entry.Outcome = "error"
+			entry.Error = c.GetString(auditErrorKey)
+		}
+		if err := appendAudit(entry); err != nil {
+			log.Printf("Unable to write the audit log: %v", err)
+		}
+	}
+}
+
+// Function:     appendAudit
+//
+// Description:  This function adds a line to the end of the audit log.
+//
+// Inputs:
+//
+//	entry      The entry to add
+func appendAudit(entry AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	auditLock.Lock()
+	defer auditLock.Unlock()
+	if err := os.MkdirAll(filepath.Dir(auditFile()), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(auditFile(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// Function:     parseSince
+//
+// Description:  This function reads the time for the --since flag. It can be a
+//
+//	duration back from now, like 2h, a date, or a RFC3339 time.
+//
+// Inputs:
+//
+//	since      The value given
+func parseSince(since string) (time.Time, error) {
+	if dur, err := time.ParseDuration(since); err == nil {
+		return time.Now().Add(-dur), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, since, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q isn't a duration, date, or time", since)
+}
+
+// Function:     queryAudit
+//
+// Description:  This function prints the lines of the audit log that match. A zero time
+//
+//	or empty template matches everything. A tail above zero only prints that
+//	many of the last lines.
+//
+// Inputs:
+//
+//	since      Only entries at or after this time
+//	template   Only entries for this template
+//	tail       The number of the last entries to print
+func queryAudit(since time.Time, template string, tail int) error {
+	file, err := os.Open(auditFile())
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			continue
+		}
+		if !since.IsZero() && entry.Time.Before(since) {
+			continue
+		}
+		if template != "" && entry.Template != template {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if tail > 0 && len(lines) > tail {
+		lines = lines[len(lines)-tail:]
+	}
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	return nil
+}
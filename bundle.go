@@ -0,0 +1,503 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The version of the bundle layout written by exportTemplates.
+const bundleVersion = 1
+
+// A partial used with {{> name}}. The name is the second group.
+var partialRefRe = regexp.MustCompile(`(\{\{~?>\s*)([\w.\-]+)`)
+
+// Struct:       BundleTemplate
+//
+// Description:  This tells about one template in a bundle. File is the name of the
+//
+//	template file. Bundles without it have json templates.
+type BundleTemplate struct {
+	Name        string `json:"name"`
+	File        string `json:"file,omitempty"`
+	Format      string `json:"format"`
+	Description string `json:"description,omitempty"`
+}
+
+// Struct:       BundleManifest
+//
+// Description:  This is the manifest.json of a bundle. It lists everything in it. The
+//
+//	templates are in dialogs/, the themes in themes/, the assets in assets/,
+//	and the partials in partials/. Partials are given by their file name.
+type BundleManifest struct {
+	Version   int              `json:"version"`
+	Created   time.Time        `json:"created"`
+	Templates []BundleTemplate `json:"templates"`
+	Themes    []string         `json:"themes,omitempty"`
+	Assets    []string         `json:"assets,omitempty"`
+	Partials  []string         `json:"partials,omitempty"`
+}
+
+// Function:     cleanBundlePath
+//
+// Description:  This function checks that a path in a bundle stays inside the
+//
+//	directory it is put in.
+//
+// Inputs:
+//
+//	name       The path to check
+func cleanBundlePath(name string) (string, error) {
+	clean := path.Clean(filepath.ToSlash(name))
+	if clean == "." || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("the path %q isn't inside the bundle", name)
+	}
+	return clean, nil
+}
+
+// Function:     exportTemplates
+//
+// Description:  This function writes the templates, with the themes, assets, and
+//
+//	partials they use, to a gzipped tar file along with a manifest. Only the
+//	user's partials are put in since everyone has the built-in ones.
+//
+// Inputs:
+//
+//	names      The names of the templates to put in the bundle
+//	out        The file to write
+func exportTemplates(names []string, out string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("you didn't give any templates to export")
+	}
+	manifest := BundleManifest{Version: bundleVersion, Created: time.Now()}
+	files := make(map[string][]byte)
+	themes := make(map[string]bool)
+	partials := make(map[string]bool)
+	layers := templateLayers()
+	for _, name := range names {
+		content, layer, err := findTemplate(layers, name)
+		if err != nil {
+			return err
+		}
+		file := layer.file(name)
+		tmpl, err := parseTemplate(name, file, string(content))
+		if err != nil {
+			return err
+		}
+		files["dialogs/"+file] = content
+		used := partialRefs(content)
+		if html, err := fs.ReadFile(layer.Files, name+".html"); err == nil && tmpl.Kind != "json" {
+			files["dialogs/"+name+".html"] = html
+			used = append(used, partialRefs(html)...)
+		}
+		manifest.Templates = append(manifest.Templates, BundleTemplate{
+			Name:        name,
+			File:        file,
+			Format:      tmpl.Meta.Format,
+			Description: tmpl.Meta.Description,
+		})
+
+		//
+		// The assets are next to the template.
+		//
+		for _, asset := range tmpl.Meta.Assets {
+			clean, err := cleanBundlePath(asset)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			data, err := fs.ReadFile(layer.Files, clean)
+			if err != nil {
+				return fmt.Errorf("%s uses the asset %s, but it can't be read: %w", name, asset, err)
+			}
+			if _, ok := files["assets/"+clean]; !ok {
+				manifest.Assets = append(manifest.Assets, clean)
+			}
+			files["assets/"+clean] = data
+		}
+		if tmpl.Meta.Theme != "" && !themes[tmpl.Meta.Theme] {
+			themefile := findResource("themes", fmt.Sprintf("%s.json", tmpl.Meta.Theme))
+			if themefile == "" {
+				return fmt.Errorf("%s uses the theme %s, but it doesn't exist", name, tmpl.Meta.Theme)
+			}
+			data, err := os.ReadFile(themefile)
+			if err != nil {
+				return err
+			}
+			themes[tmpl.Meta.Theme] = true
+			files["themes/"+tmpl.Meta.Theme+".json"] = data
+			manifest.Themes = append(manifest.Themes, tmpl.Meta.Theme)
+		}
+
+		//
+		// A partial can use other partials, so those go in too.
+		//
+		for len(used) > 0 {
+			partial := used[0]
+			used = used[1:]
+			if partials[partial] {
+				continue
+			}
+			partials[partial] = true
+			partialFile, data, err := userPartial(partial)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if partialFile == "" {
+				continue
+			}
+			files["partials/"+partialFile] = data
+			manifest.Partials = append(manifest.Partials, partialFile)
+			used = append(used, partialRefs(data)...)
+		}
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", " ")
+	if err != nil {
+		return err
+	}
+
+	//
+	// Write the manifest first so it can be read without going through everything.
+	//
+	file, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	zipper := gzip.NewWriter(file)
+	archive := tar.NewWriter(zipper)
+	writeFile := func(name string, data []byte) error {
+		if err := archive.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: manifest.Created,
+		}); err != nil {
+			return err
+		}
+		_, err := archive.Write(data)
+		return err
+	}
+	if err := writeFile("manifest.json", manifestData); err != nil {
+		return err
+	}
+	var paths []string
+	for name := range files {
+		paths = append(paths, name)
+	}
+	sort.Strings(paths)
+	for _, name := range paths {
+		if err := writeFile(name, files[name]); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	if err := zipper.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Function:     readBundle
+//
+// Description:  This function reads the manifest and files of a bundle.
+//
+// Inputs:
+//
+//	in         The bundle file
+func readBundle(in string) (BundleManifest, map[string][]byte, error) {
+	var manifest BundleManifest
+	files := make(map[string][]byte)
+	file, err := os.Open(in)
+	if err != nil {
+		return manifest, nil, err
+	}
+	defer file.Close()
+	zipper, err := gzip.NewReader(file)
+	if err != nil {
+		return manifest, nil, fmt.Errorf("%s isn't a gzipped bundle: %w", in, err)
+	}
+	archive := tar.NewReader(zipper)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return manifest, nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name, err := cleanBundlePath(header.Name)
+		if err != nil {
+			return manifest, nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(archive, 64<<20))
+		if err != nil {
+			return manifest, nil, err
+		}
+		files[name] = data
+	}
+	data, ok := files["manifest.json"]
+	if !ok {
+		return manifest, nil, fmt.Errorf("%s doesn't have a manifest.json", in)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, nil, fmt.Errorf("the manifest of %s can't be read: %w", in, err)
+	}
+	if manifest.Version > bundleVersion {
+		return manifest, nil, fmt.Errorf("%s is a version %d bundle, but only version %d is known", in, manifest.Version, bundleVersion)
+	}
+	return manifest, files, nil
+}
+
+// Function:     importTemplates
+//
+// Description:  This function puts the templates, themes, assets, and partials of a
+//
+//	bundle into the user's directories. The prefix is put on the names of the
+//	templates, themes, and partials. Nothing is written if any of them are
+//	already there with different contents unless force is given. A partial
+//	that would take the place of another one, like a built-in one, is never
+//	written.
+//
+// Inputs:
+//
+//	in         The bundle file
+//	prefix     The prefix for the names of the templates and themes
+//	force      True to write over what is there
+func importTemplates(in string, prefix string, force bool) ([]string, error) {
+	manifest, files, err := readBundle(in)
+	if err != nil {
+		return nil, err
+	}
+	dialogsDir := userDir("dialogs")
+	themesDir := userDir("themes")
+	partialsDir := userDir("partials")
+	writes := make(map[string][]byte)
+	var imported []string
+
+	//
+	// The names of the partials are needed to prefix where they are used.
+	//
+	bundled := make(map[string]bool)
+	for _, partial := range manifest.Partials {
+		bundled[strings.TrimSuffix(partial, path.Ext(partial))] = true
+	}
+	usePrefix := func(data []byte) []byte {
+		if prefix == "" || len(bundled) == 0 {
+			return data
+		}
+		return prefixPartials(data, bundled, prefix)
+	}
+	existing := loadPartials()
+	for _, partial := range manifest.Partials {
+		clean, err := cleanBundlePath(partial)
+		if err != nil {
+			return nil, err
+		}
+		ext := path.Ext(clean)
+		known := false
+		for _, partialExt := range partialExtensions {
+			known = known || ext == partialExt
+		}
+		data, ok := files["partials/"+clean]
+		if !ok || !known || path.Base(clean) != clean {
+			return nil, fmt.Errorf("the bundle is missing the partial %s", partial)
+		}
+		target := filepath.Join(partialsDir, filepath.Base(prefix+clean))
+		if _, ok := existing[strings.TrimSuffix(filepath.Base(target), ext)]; ok && !fileExists(target) {
+			return nil, fmt.Errorf("the partial %s would take the place of one you already have, so nothing was imported (use --prefix)", strings.TrimSuffix(filepath.Base(target), ext))
+		}
+		writes[target] = usePrefix(data)
+	}
+
+	for _, theme := range manifest.Themes {
+		data, ok := files["themes/"+theme+".json"]
+		if !ok {
+			return nil, fmt.Errorf("the bundle is missing the theme %s", theme)
+		}
+		writes[filepath.Join(themesDir, filepath.Base(prefix+theme)+".json")] = data
+	}
+	for _, asset := range manifest.Assets {
+		clean, err := cleanBundlePath(asset)
+		if err != nil {
+			return nil, err
+		}
+		data, ok := files["assets/"+clean]
+		if !ok {
+			return nil, fmt.Errorf("the bundle is missing the asset %s", asset)
+		}
+		writes[filepath.Join(dialogsDir, filepath.FromSlash(clean))] = data
+	}
+	for _, entry := range manifest.Templates {
+		file := entry.File
+		if file == "" {
+			file = entry.Name + ".json"
+		}
+		file, err := cleanBundlePath(file)
+		if err != nil {
+			return nil, err
+		}
+		data, ok := files["dialogs/"+file]
+		if !ok || templateKind(file) == "" {
+			return nil, fmt.Errorf("the bundle is missing the template %s", entry.Name)
+		}
+		name := filepath.Base(prefix + entry.Name)
+		if prefix != "" {
+			data, err = renameTemplate(entry.Name, file, data, name, prefix)
+			if err != nil {
+				return nil, err
+			}
+		}
+		writes[filepath.Join(dialogsDir, name+filepath.Ext(file))] = usePrefix(data)
+		if html, ok := files["dialogs/"+entry.Name+".html"]; ok {
+			writes[filepath.Join(dialogsDir, name+".html")] = usePrefix(html)
+		}
+		imported = append(imported, name)
+	}
+
+	//
+	// Check for all of the conflicts before writing anything.
+	//
+	if !force {
+		var conflicts []string
+		for target, data := range writes {
+			if existing, err := os.ReadFile(target); err == nil && !bytes.Equal(existing, data) {
+				conflicts = append(conflicts, target)
+			}
+		}
+		if len(conflicts) > 0 {
+			sort.Strings(conflicts)
+			return nil, fmt.Errorf("these are already there, so nothing was imported (use --force or --prefix):\n  %s", strings.Join(conflicts, "\n  "))
+		}
+	}
+	for target, data := range writes {
+		if filepath.Dir(target) == dialogsDir && (templateKind(target) != "" || filepath.Ext(target) == ".html") {
+			//
+			// A template or its html file written over is kept as a revision.
+			//
+			if err := writeTemplate(target, data); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	//
+	// What came from someone else can't run scripts until the user says so.
+	//
+	if err := setTemplateTrust(imported, false); err != nil {
+		return nil, err
+	}
+	return imported, nil
+}
+
+// Function:     renameTemplate
+//
+// Description:  This function gives a template the new name and prefixes the theme
+//
+//	it uses. Only templates with a header need it changed.
+//
+// Inputs:
+//
+//	name       The name of the template in the bundle
+//	file       The name of the template file in the bundle
+//	data       The contents of the template
+//	newName    The name to give it
+//	prefix     The prefix for the theme
+func renameTemplate(name string, file string, data []byte, newName string, prefix string) ([]byte, error) {
+	tmpl, err := parseTemplate(name, file, string(data))
+	if err != nil {
+		return nil, err
+	}
+	if !tmpl.Header {
+		return data, nil
+	}
+	tmpl.Meta.Name = newName
+	if tmpl.Meta.Theme != "" {
+		tmpl.Meta.Theme = prefix + tmpl.Meta.Theme
+	}
+	return []byte(tmpl.Meta.header() + tmpl.Body), nil
+}
+
+// Function:     partialRefs
+//
+// Description:  This function gives the names of the partials a template or partial
+//
+//	uses with {{> name}}.
+//
+// Inputs:
+//
+//	data       The contents to look in
+func partialRefs(data []byte) []string {
+	var names []string
+	for _, match := range partialRefRe.FindAllSubmatch(data, -1) {
+		names = append(names, string(match[2]))
+	}
+	return names
+}
+
+// Function:     userPartial
+//
+// Description:  This function finds a partial in the user's partials directory. The
+//
+//	file name is empty for a partial that comes from somewhere else, like a
+//	built-in one. A partial that isn't anywhere is an error.
+//
+// Inputs:
+//
+//	name       The name of the partial
+func userPartial(name string) (string, []byte, error) {
+	dir := userDir("partials")
+	for _, ext := range partialExtensions {
+		if data, err := os.ReadFile(filepath.Join(dir, name+ext)); err == nil {
+			return name + ext, data, nil
+		}
+	}
+	if _, ok := loadPartials()[name]; ok {
+		return "", nil, nil
+	}
+	return "", nil, fmt.Errorf("it uses the partial %s, but it doesn't exist", name)
+}
+
+// Function:     prefixPartials
+//
+// Description:  This function puts the prefix on the names of the partials used that
+//
+//	came in the bundle. Others, like the built-in ones, are left as they are.
+//
+// Inputs:
+//
+//	data       The contents of a template, html file, or partial
+//	names      The names of the partials in the bundle
+//	prefix     The prefix for their names
+func prefixPartials(data []byte, names map[string]bool, prefix string) []byte {
+	return partialRefRe.ReplaceAllFunc(data, func(match []byte) []byte {
+		parts := partialRefRe.FindSubmatch(match)
+		if !names[string(parts[2])] {
+			return match
+		}
+		return append(append([]byte(nil), parts[1]...), []byte(prefix+string(parts[2]))...)
+	})
+}
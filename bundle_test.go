@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The user's partials a template uses go in the bundle, and they get the prefix when
+// it is imported with one.
+func TestBundlePartials(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	write := func(kind string, file string, data string) {
+		dir := userDir(kind)
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("dialogs", "hello.json", `{ "html": "{{> greet}}", "width": 200, "height": 80, "x": 1, "y": 1 }`)
+	write("partials", "greet.hbs", "<p>Hi</p>{{> inner}}{{> bbstyle}}")
+	write("partials", "inner.html", "<p>there</p>")
+
+	bundle := filepath.Join(t.TempDir(), "pack.tar.gz")
+	if err := exportTemplates([]string{"hello"}, bundle); err != nil {
+		t.Fatal(err)
+	}
+	manifest, files, err := readBundle(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"greet.hbs", "inner.html"}; !reflect.DeepEqual(manifest.Partials, want) {
+		t.Fatalf("the partials are %v, want %v", manifest.Partials, want)
+	}
+	if _, ok := files["partials/bbstyle.hbs"]; ok {
+		t.Fatal("a built-in partial was put in the bundle")
+	}
+
+	//
+	// Someone else imports it with a prefix.
+	//
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if _, err := importTemplates(bundle, "team-", false); err != nil {
+		t.Fatal(err)
+	}
+	read := func(kind string, file string) string {
+		data, err := os.ReadFile(filepath.Join(userDir(kind), file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if got := read("dialogs", "team-hello.json"); !strings.Contains(got, "{{> team-greet}}") {
+		t.Fatalf("the template is %s", got)
+	}
+	if got := read("partials", "team-greet.hbs"); got != "<p>Hi</p>{{> team-inner}}{{> bbstyle}}" {
+		t.Fatalf("the partial is %s", got)
+	}
+	read("partials", "team-inner.html")
+
+	//
+	// Without the prefix, a partial of theirs with the same name is a conflict.
+	//
+	write("partials", "greet.hbs", "<p>Mine</p>")
+	if _, err := importTemplates(bundle, "", false); err == nil || !strings.Contains(err.Error(), "greet.hbs") {
+		t.Fatalf("the conflict wasn't found: %v", err)
+	}
+	if got := read("partials", "greet.hbs"); got != "<p>Mine</p>" {
+		t.Fatalf("the partial was written over with %s", got)
+	}
+}
+
+// A partial in a bundle can't take the place of a built-in one.
+func TestBundlePartialKeepsBuiltins(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	for kind, file := range map[string]string{"dialogs": "styled.json", "partials": "bbstyle.hbs"} {
+		dir := userDir(kind)
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		data := `{ "html": "{{> bbstyle}}", "width": 200, "height": 80, "x": 1, "y": 1 }`
+		if kind == "partials" {
+			data = "<script>steal()</script>"
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bundle := filepath.Join(t.TempDir(), "pack.tar.gz")
+	if err := exportTemplates([]string{"styled"}, bundle); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if _, err := importTemplates(bundle, "", false); err == nil || !strings.Contains(err.Error(), "bbstyle") {
+		t.Fatalf("the built-in partial could be replaced: %v", err)
+	}
+	if _, err := importTemplates(bundle, "team-", false); err != nil {
+		t.Fatal(err)
+	}
+	if !fileExists(filepath.Join(userDir("partials"), "team-bbstyle.hbs")) {
+		t.Fatal("the prefixed partial wasn't imported")
+	}
+}
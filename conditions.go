@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Struct:       Condition
+//
+// Description:  This is a test on the value of a field. The field is the id or the
+//
+//	name of an item. The op is one of eq, ne, contains, empty, or notempty.
+//	Values are compared as strings, so a checked checkbox is "true".
+type Condition struct {
+	Field string `json:"field" binding:"required"`
+	Op    string `json:"op"`
+	Value string `json:"value"`
+}
+
+// The condition operators.
+var conditionOps = []string{"eq", "ne", "contains", "empty", "notempty"}
+
+// Function:     check
+//
+// Description:  This method makes sure the condition can be used.
+func (cond Condition) check() error {
+	if cond.Field == "" {
+		return fmt.Errorf("a condition needs a field")
+	}
+	for _, op := range conditionOps {
+		if cond.Op == op || (cond.Op == "" && op == "eq") {
+			return nil
+		}
+	}
+	return fmt.Errorf("the condition on %q has an unknown op %q", cond.Field, cond.Op)
+}
+
+// Function:     matches
+//
+// Description:  This method tests the condition against the values given so far. The
+//
+//	field has to be resolved to an item name first.
+//
+// Inputs:
+//
+//	values     The values keyed by the item name
+func (cond Condition) matches(values map[string]interface{}) bool {
+	str := ""
+	if val, ok := values[cond.Field]; ok && val != nil {
+		str = fmt.Sprint(val)
+	}
+	switch cond.Op {
+	case "ne":
+		return str != cond.Value
+	case "contains":
+		return strings.Contains(str, cond.Value)
+	case "empty":
+		return str == ""
+	case "notempty":
+		return str != ""
+	}
+	return str == cond.Value
+}
+
+// Function:     fieldName
+//
+// Description:  This method resolves the field of a condition to the name of the item
+//
+//	it is about. An id is looked up. Anything else is taken as a name.
+//
+// Inputs:
+//
+//	field      The field from a condition
+func (d ModalDialog) fieldName(field string) string {
+	if item, ok := d.itemByField(field); ok {
+		return item.Name
+	}
+	return field
+}
+
+// Function:     itemByField
+//
+// Description:  This method finds the item a condition field is about. Ids are tried
+//
+//	before names.
+//
+// Inputs:
+//
+//	field      The field from a condition
+func (d ModalDialog) itemByField(field string) (DialogItem, bool) {
+	for _, item := range d.Items {
+		if item.Id == field {
+			return item, true
+		}
+	}
+	for _, item := range d.Items {
+		if item.Name == field {
+			return item, true
+		}
+	}
+	return DialogItem{}, false
+}
+
+// Function:     isVisible
+//
+// Description:  This method tells if an item is shown for the values given. An item
+//
+//	is hidden if its showIf doesn't match or the item it depends on is hidden.
+//
+// Inputs:
+//
+//	item       The item to test
+//	values     The values keyed by the item name
+func (d ModalDialog) isVisible(item DialogItem, values map[string]interface{}) bool {
+	for depth := 0; item.ShowIf != nil; depth++ {
+		if depth > len(d.Items) {
+			//
+			// A loop. checkShowIf keeps these out, so just hide it.
+			//
+			return false
+		}
+		cond := *item.ShowIf
+		cond.Field = d.fieldName(cond.Field)
+		if !cond.matches(values) {
+			return false
+		}
+		next, ok := d.itemByField(item.ShowIf.Field)
+		if !ok {
+			return true
+		}
+		item = next
+	}
+	return true
+}
+
+// Function:     visibleValues
+//
+// Description:  This method removes the values of hidden items.
+//
+// Inputs:
+//
+//	values     The values keyed by the item name
+func (d ModalDialog) visibleValues(values map[string]interface{}) map[string]interface{} {
+	visible := make(map[string]interface{}, len(values))
+	for key, val := range values {
+		visible[key] = val
+	}
+	for _, item := range d.Items {
+		if !d.isVisible(item, values) {
+			delete(visible, item.Name)
+		}
+	}
+	return visible
+}
+
+// Function:     checkShowIf
+//
+// Description:  This method makes sure the showIf of every item refers to an item in
+//
+//	the dialog and that they don't depend on each other in a loop.
+func (d ModalDialog) checkShowIf() error {
+	for _, item := range d.Items {
+		if item.ShowIf == nil {
+			continue
+		}
+		if err := item.ShowIf.check(); err != nil {
+			return fmt.Errorf("item %q: %w", item.Id, err)
+		}
+		seen := map[string]bool{item.Id: true}
+		for next := item; next.ShowIf != nil; {
+			found, ok := d.itemByField(next.ShowIf.Field)
+			if !ok {
+				return fmt.Errorf("item %q shows if %q, but there isn't an item with that id", item.Id, next.ShowIf.Field)
+			}
+			if seen[found.Id] {
+				return fmt.Errorf("item %q has a showIf that loops back on itself", item.Id)
+			}
+			seen[found.Id] = true
+			next = found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// How long to wait for more changes before sending the dialog again. Editors often
+// write a file more than once when saving it.
+const devSettle = 150 * time.Millisecond
+
+// Function:     devTemplate
+//
+// Description:  This function sends a template to the BulletinBoard and sends it again
+//
+//	each time the template, a partial, or its theme is saved. A new copy takes
+//	the place of the one shown. Each result is printed as it comes back.
+//
+// Inputs:
+//
+//	name       The name of the template
+//	args       The values given after the template name
+func devTemplate(name string, args []string) error {
+	_, layer, err := findTemplate(templateLayers(), name)
+	if err != nil {
+		return err
+	}
+	if layer.readOnly() {
+		return fmt.Errorf("%s is built in, so it can't be changed. Use bb template copy %s <newname> first", name, name)
+	}
+	templatefile := layer.path(layer.file(name))
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	//
+	// The directories are watched since many editors save by replacing the file.
+	//
+	watched := map[string]bool{}
+	watch := func(dir string) {
+		if dir == "" || watched[dir] {
+			return
+		}
+		if err := watcher.Add(dir); err == nil {
+			watched[dir] = true
+		}
+	}
+	watch(filepath.Dir(templatefile))
+	for _, dir := range resourceDirs("partials") {
+		watch(dir)
+	}
+	for _, dir := range resourceDirs("themes") {
+		watch(dir)
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	fmt.Fprintf(os.Stderr, "Watching %s. Press Ctrl-C to stop.\n", templatefile)
+	cancel := devSend(name, templatefile, args)
+	var settle <-chan time.Time
+	for {
+		select {
+		case <-interrupt:
+			cancel()
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				cancel()
+				return nil
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 || !devRelevant(event.Name, templatefile) {
+				continue
+			}
+			settle = time.After(devSettle)
+
+		case err, ok := <-watcher.Errors:
+			if ok {
+				fmt.Fprintf(os.Stderr, "Watch error: %v\n", err)
+			}
+
+		case <-settle:
+			settle = nil
+			cancel()
+			cancel = devSend(name, templatefile, args)
+		}
+	}
+}
+
+// Function:     devRelevant
+//
+// Description:  This function tells if a changed file means the dialog has to be sent
+//
+//	again. That is the template itself, its html file, any partial, or any
+//	theme.
+//
+// Inputs:
+//
+//	changed        The file that changed
+//	templatefile   The template being worked on
+func devRelevant(changed string, templatefile string) bool {
+	if filepath.Clean(changed) == filepath.Clean(templatefile) {
+		return true
+	}
+	if filepath.Clean(changed) == filepath.Clean(strings.TrimSuffix(templatefile, filepath.Ext(templatefile))+".html") {
+		return true
+	}
+	base := filepath.Base(changed)
+	if strings.HasPrefix(base, ".") || strings.HasSuffix(base, "~") {
+		return false
+	}
+	switch filepath.Base(filepath.Dir(changed)) {
+	case "partials", "themes":
+		return true
+	}
+	return false
+}
+
+// Function:     devSend
+//
+// Description:  This function renders the template and sends it to the BulletinBoard
+//
+//	without waiting for the answer. The answer is printed when it comes. The
+//	function given back takes the dialog down if it hasn't been answered.
+//
+// Inputs:
+//
+//	name           The name of the template
+//	templatefile   The template file
+//	args           The values given after the template name
+func devSend(name string, templatefile string, args []string) context.CancelFunc {
+	content, err := os.ReadFile(templatefile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", time.Now().Format("15:04:05"), err)
+		return func() {}
+	}
+	endpoint, payload, err := renderTemplate(name, templatefile, string(content), args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", time.Now().Format("15:04:05"), err)
+		return func() {}
+	}
+	fmt.Fprintf(os.Stderr, "%s: Sent %s to /api/%s.\n", time.Now().Format("15:04:05"), name, endpoint)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, fmt.Sprintf("http://localhost:9697/api/%s", endpoint), strings.NewReader(payload))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		if endpoint == "dialog" {
+			req.Header.Set(trustHeader, readTrustToken())
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			if ctx.Err() == nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return
+		}
+		fmt.Println(string(body))
+	}()
+
+	//
+	// Wait for the request to end so the BulletinBoard sees it go before the next
+	// dialog is sent.
+	//
+	return func() {
+		cancel()
+		<-finished
+	}
+}
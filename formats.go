@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// The numbers of a raw dialog. In a yaml or toml template they can be given as a
+// string so they can come from the data, like width: "{{data3}}".
+var rawDialogNumbers = []string{"width", "height", "x", "y", "timeout"}
+
+// The fields of a dialog item that are strings in json. Yaml and toml read values like
+// false or 10 as a bool or a number, so they are turned back into strings.
+var itemStringFields = []string{"value", "min", "max"}
+
+// Function:     templateKind
+//
+// Description:  This function gives the language a template file is written in from
+//
+//	its extension: json, yaml, or toml. Other files give an empty string.
+//
+// Inputs:
+//
+//	file       The name of the template file
+func templateKind(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	}
+	return ""
+}
+
+// Function:     decode
+//
+// Description:  This method reads the body of a yaml or toml template. Errors give the
+//
+//	line of the template file. The values of the items are made strings.
+func (tmpl Template) decode() (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	switch tmpl.Kind {
+	case "yaml":
+		if err := yaml.Unmarshal([]byte(tmpl.Body), &values); err != nil {
+			return nil, errors.New(renderLineRe.ReplaceAllStringFunc(err.Error(), func(match string) string {
+				line, _ := strconv.Atoi(strings.TrimPrefix(match, "line "))
+				return fmt.Sprintf("line %d", line+tmpl.BodyLine-1)
+			}))
+		}
+	case "toml":
+		if err := toml.Unmarshal([]byte(tmpl.Body), &values); err != nil {
+			var decode *toml.DecodeError
+			if errors.As(err, &decode) {
+				row, column := decode.Position()
+				return nil, fmt.Errorf("%w at line %d, column %d", err, row+tmpl.BodyLine-1, column)
+			}
+			return nil, err
+		}
+	default:
+		if err := json.Unmarshal([]byte(tmpl.Body), &values); err != nil {
+			return nil, jsonErrorPosition(err, tmpl.Body, tmpl.BodyLine)
+		}
+		return values, nil
+	}
+	stringifyItems(values)
+	if pages, ok := values["pages"].([]interface{}); ok {
+		for _, page := range pages {
+			if page, ok := page.(map[string]interface{}); ok {
+				stringifyItems(page)
+			}
+		}
+	}
+	return values, nil
+}
+
+// Function:     stringifyItems
+//
+// Description:  This function makes the bool and number fields of the items of a
+//
+//	dialog read from yaml or toml into strings like json templates have.
+//
+// Inputs:
+//
+//	dialog     The dialog or wizard page
+func stringifyItems(dialog map[string]interface{}) {
+	items, _ := dialog["items"].([]interface{})
+	for _, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, key := range itemStringFields {
+			switch value := fields[key].(type) {
+			case nil, string, map[string]interface{}, []interface{}:
+			case float64:
+				fields[key] = strconv.FormatFloat(value, 'f', -1, 64)
+			case float32:
+				fields[key] = strconv.FormatFloat(float64(value), 'f', -1, 32)
+			default:
+				fields[key] = fmt.Sprint(value)
+			}
+		}
+	}
+}
+
+// Function:     bodyJson
+//
+// Description:  This method gives the body of the template as json. A json template
+//
+//	is given as it is.
+func (tmpl Template) bodyJson() (string, error) {
+	if tmpl.Kind == "json" {
+		return tmpl.Body, nil
+	}
+	values, err := tmpl.decode()
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(values)
+	return string(body), err
+}
+
+// Function:     rawDialog
+//
+// Description:  This method makes the dialog of a raw yaml or toml template. Every
+//
+//	string in it is rendered with the data. The html can be in a file with
+//	the same name as the template and the html extension.
+//
+// Inputs:
+//
+//	file       The path of the template file
+//	data       The values for rendering the template
+func (tmpl Template) rawDialog(file string, data map[string]string) (Dialog, error) {
+	var dialog Dialog
+	values, err := tmpl.decode()
+	if err != nil {
+		return dialog, err
+	}
+	if html, ok := values["html"].(string); !ok || strings.TrimSpace(html) == "" {
+		sidecar := strings.TrimSuffix(file, filepath.Ext(file)) + ".html"
+		content, err := os.ReadFile(sidecar)
+		if err != nil {
+			return dialog, fmt.Errorf("it doesn't have html and %s can't be read", filepath.Base(sidecar))
+		}
+		values["html"] = string(content)
+	}
+	rendered, err := renderValues(values, data, tmpl.Meta.Theme)
+	if err != nil {
+		return dialog, err
+	}
+	values = rendered.(map[string]interface{})
+	for _, key := range rawDialogNumbers {
+		if text, ok := values[key].(string); ok {
+			number, err := strconv.Atoi(strings.TrimSpace(text))
+			if err != nil {
+				return dialog, fmt.Errorf("the %s is %q, but it has to be a number", key, text)
+			}
+			values[key] = number
+		}
+	}
+	body, err := json.Marshal(values)
+	if err != nil {
+		return dialog, err
+	}
+	err = json.Unmarshal(body, &dialog)
+	return dialog, err
+}
+
+// Function:     renderValues
+//
+// Description:  This function renders each string in values read from a template. The
+//
+//	partials go in as they are since nothing has to be escaped.
+//
+// Inputs:
+//
+//	value      The value to render
+//	data       The values for rendering
+//	theme      The theme for the color helper
+func renderValues(value interface{}, data map[string]string, theme string) (interface{}, error) {
+	switch value := value.(type) {
+	case string:
+		if !strings.Contains(value, "{{") {
+			return value, nil
+		}
+		return renderContents(value, data, theme, loadPartials())
+	case map[string]interface{}:
+		for key, item := range value {
+			rendered, err := renderValues(item, data, theme)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			value[key] = rendered
+		}
+	case []interface{}:
+		for i, item := range value {
+			rendered, err := renderValues(item, data, theme)
+			if err != nil {
+				return nil, err
+			}
+			value[i] = rendered
+		}
+	}
+	return value, nil
+}
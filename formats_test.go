@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Bools and numbers in the items of yaml and toml templates are read as strings.
+func TestItemValuesFromYamlAndToml(t *testing.T) {
+	templates := map[string]string{
+		"form.yaml": `title: Form
+items:
+  - { modaltype: checkbox, name: Agree, id: agree, value: false }
+  - { modaltype: input, htmltype: number, name: Count, id: count, value: 10, min: 0, max: 2.5 }
+buttons:
+  - { name: OK, id: ok, action: save }
+`,
+		"form.toml": `title = "Form"
+[[items]]
+modaltype = "checkbox"
+name = "Agree"
+id = "agree"
+value = false
+[[items]]
+modaltype = "input"
+htmltype = "number"
+name = "Count"
+id = "count"
+value = 10
+min = 0
+max = 2.5
+[[buttons]]
+name = "OK"
+id = "ok"
+action = "save"
+`,
+	}
+	for file, content := range templates {
+		endpoint, payload, err := renderTemplate("form", file, content, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		if endpoint != "modal" {
+			t.Fatalf("%s: the endpoint is %s", file, endpoint)
+		}
+		var dialog ModalDialog
+		if err := json.Unmarshal([]byte(payload), &dialog); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		if len(dialog.Items) != 2 {
+			t.Fatalf("%s: the items are %+v", file, dialog.Items)
+		}
+		agree, count := dialog.Items[0], dialog.Items[1]
+		if agree.Value != "false" || count.Value != "10" || count.Min != "0" || count.Max != "2.5" {
+			t.Fatalf("%s: the items are %+v", file, dialog.Items)
+		}
+	}
+}
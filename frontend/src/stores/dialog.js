@@ -2,3 +2,4 @@ import { writable } from 'svelte/store';
 
 export const dialog = writable({});
 
+export const dialogErrors = writable({});
@@ -2,3 +2,5 @@ import { writable } from 'svelte/store';
 
 export const message = writable('');
 
+export const messageInfo = writable({ title: '', level: 'info', html: '' });
+
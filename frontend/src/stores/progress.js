@@ -0,0 +1,10 @@
+import { writable } from 'svelte/store';
+
+export const progress = writable({
+  id: '',
+  title: '',
+  total: 0,
+  current: 0,
+  status: ''
+});
+
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// The directory next to the templates that keeps their old revisions. It starts with
+// a dot so it isn't taken for a template.
+const historyDirName = ".history"
+
+// The layout of the names of the revisions. They sort in the order they were made.
+const historyStamp = "20060102-150405.000"
+
+// The number of revisions kept for each template.
+const historyMax = 50
+
+// Struct:       Revision
+//
+// Description:  This is one old copy of a template. Number 1 is the newest.
+type Revision struct {
+	Number int
+	Id     string
+	Time   time.Time
+	Path   string
+	Size   int64
+}
+
+// Function:     historyDir
+//
+// Description:  This function gives the directory the revisions of a template file
+//
+//	are kept in.
+//
+// Inputs:
+//
+//	file       The path of the template file
+func historyDir(file string) string {
+	return filepath.Join(filepath.Dir(file), historyDirName, filepath.Base(file))
+}
+
+// Function:     writeTemplate
+//
+// Description:  This function writes a template file. What was there is kept as a
+//
+//	revision first so a bad save can be undone with bb template restore.
+//
+// Inputs:
+//
+//	file       The path of the template file
+//	data       The new contents
+func writeTemplate(file string, data []byte) error {
+	if existing, err := os.ReadFile(file); err == nil && !bytes.Equal(existing, data) {
+		if err := saveRevision(file, existing); err != nil {
+			return fmt.Errorf("the old copy of %s can't be kept, so it wasn't written: %w", filepath.Base(file), err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
+
+// Function:     saveRevision
+//
+// Description:  This function keeps a copy of a template as a revision. A copy the
+//
+//	same as the newest revision isn't kept again. Only the newest historyMax
+//	revisions are kept.
+//
+// Inputs:
+//
+//	file       The path of the template file
+//	data       The contents to keep
+func saveRevision(file string, data []byte) error {
+	revisions, err := templateRevisions(file)
+	if err != nil {
+		return err
+	}
+	if len(revisions) > 0 {
+		if newest, err := os.ReadFile(revisions[0].Path); err == nil && bytes.Equal(newest, data) {
+			return nil
+		}
+	}
+	dir := historyDir(file)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	target := filepath.Join(dir, time.Now().Format(historyStamp)+filepath.Ext(file))
+	if err := os.WriteFile(target, data, 0644); err != nil {
+		return err
+	}
+	for i := historyMax - 1; i < len(revisions); i++ {
+		os.Remove(revisions[i].Path)
+	}
+	return nil
+}
+
+// Function:     templateRevisions
+//
+// Description:  This function gives the revisions of a template file, newest first.
+//
+// Inputs:
+//
+//	file       The path of the template file
+func templateRevisions(file string) ([]Revision, error) {
+	entries, err := os.ReadDir(historyDir(file))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var revisions []Revision
+	for _, entry := range entries {
+		id := FilenameWithoutExtension(entry.Name())
+		stamp, err := time.ParseInLocation(historyStamp, id, time.Local)
+		if entry.IsDir() || err != nil {
+			continue
+		}
+		revision := Revision{Id: id, Time: stamp, Path: filepath.Join(historyDir(file), entry.Name())}
+		if info, err := entry.Info(); err == nil {
+			revision.Size = info.Size()
+		}
+		revisions = append(revisions, revision)
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Id > revisions[j].Id
+	})
+	for i := range revisions {
+		revisions[i].Number = i + 1
+	}
+	return revisions, nil
+}
+
+// Function:     userTemplateFile
+//
+// Description:  This function gives the path of one of the user's templates. Only
+//
+//	those have revisions since BulletinBoard only writes there. A deleted
+//	template is found by its revisions. A name ending in .html gives the
+//	html file of a yaml or toml template.
+//
+// Inputs:
+//
+//	name       The name of the template
+func userTemplateFile(name string) (string, error) {
+	dir := userDir("dialogs")
+	name = filepath.Base(name)
+	if filepath.Ext(name) == ".html" {
+		path := filepath.Join(dir, name)
+		if fileExists(path) || dirExists(historyDir(path)) {
+			return path, nil
+		}
+		return "", fmt.Errorf("you don't have an html file named %s", name)
+	}
+	if file := (templateLayer{Dir: dir, Files: os.DirFS(dir)}).file(name); file != "" {
+		return filepath.Join(dir, file), nil
+	}
+	for _, ext := range templateExtensions {
+		path := filepath.Join(dir, name+ext)
+		if dirExists(historyDir(path)) {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("you don't have a template named %s", name)
+}
+
+// Function:     dirExists
+//
+// Description:  This function tells if a directory exists.
+//
+// Inputs:
+//
+//	dir        The path of the directory
+func dirExists(dir string) bool {
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+// Function:     findRevision
+//
+// Description:  This function finds a revision of a template by its number in bb
+//
+//	template history or by its id.
+//
+// Inputs:
+//
+//	file       The path of the template file
+//	rev        The number or id of the revision
+func findRevision(file string, rev string) (Revision, error) {
+	revisions, err := templateRevisions(file)
+	if err != nil {
+		return Revision{}, err
+	}
+	number, numErr := strconv.Atoi(rev)
+	for _, revision := range revisions {
+		if revision.Id == rev || numErr == nil && revision.Number == number {
+			return revision, nil
+		}
+	}
+	return Revision{}, fmt.Errorf("%s doesn't have the revision %s. Use bb template history to see them", filepath.Base(file), rev)
+}
+
+// Function:     listRevisions
+//
+// Description:  This function prints the revisions of a template, newest first.
+//
+// Inputs:
+//
+//	name       The name of the template
+func listRevisions(name string) error {
+	file, err := userTemplateFile(name)
+	if err != nil {
+		return err
+	}
+	revisions, err := templateRevisions(file)
+	if err != nil {
+		return err
+	}
+	if len(revisions) == 0 {
+		fmt.Printf("%s doesn't have any revisions yet.\n", name)
+		return nil
+	}
+	for _, revision := range revisions {
+		fmt.Printf("%3d  %s  %s  %d bytes\n", revision.Number, revision.Id, revision.Time.Format("2006-01-02 15:04:05"), revision.Size)
+	}
+	return nil
+}
+
+// Function:     diffRevision
+//
+// Description:  This function prints the changes from a revision of a template to the
+//
+//	template as it is now as a unified diff.
+//
+// Inputs:
+//
+//	name       The name of the template
+//	rev        The number or id of the revision
+func diffRevision(name string, rev string) error {
+	file, err := userTemplateFile(name)
+	if err != nil {
+		return err
+	}
+	revision, err := findRevision(file, rev)
+	if err != nil {
+		return err
+	}
+	old, err := os.ReadFile(revision.Path)
+	if err != nil {
+		return err
+	}
+	//
+	// A deleted template is compared with nothing.
+	//
+	current, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var lines []string
+	if len(current) > 0 {
+		lines = difflib.SplitLines(string(current))
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(old)),
+		B:        lines,
+		FromFile: filepath.Base(file) + "@" + revision.Id,
+		ToFile:   filepath.Base(file),
+		Context:  3,
+	})
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(diff) == "" {
+		fmt.Printf("%s is the same as revision %s.\n", name, revision.Id)
+		return nil
+	}
+	fmt.Print(diff)
+	return nil
+}
+
+// Function:     restoreRevision
+//
+// Description:  This function puts a revision of a template back. The template as it
+//
+//	is now is kept as a revision, so restoring can be undone too.
+//
+// Inputs:
+//
+//	name       The name of the template
+//	rev        The number or id of the revision
+func restoreRevision(name string, rev string) (Revision, error) {
+	file, err := userTemplateFile(name)
+	if err != nil {
+		return Revision{}, err
+	}
+	revision, err := findRevision(file, rev)
+	if err != nil {
+		return revision, err
+	}
+	data, err := os.ReadFile(revision.Path)
+	if err != nil {
+		return revision, err
+	}
+	return revision, writeTemplate(file, data)
+}
@@ -8,12 +8,12 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
-	"net/url"
 	"os"
 	"path"
 	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/aymerick/raymond"
@@ -44,57 +44,119 @@ var icon []byte
 //
 // Description:       This function is used to process and render the contents of a dialog.
 //
+//	The partials and helpers are there for the template to use.
+//
+// Inputs:
+//
+//	template      The template to use
+//	data          The data to use to render the template
+//	theme         The theme the color helper uses
+func RenderDialogContents(template string, data map[string]string, theme string) (string, error) {
+	return renderContents(template, data, theme, jsonPartials(data, theme))
+}
+
+// Function:          renderContents
+//
+// Description:       This function renders a template with the partials given.
+//
 // Inputs:
 //
 //	template      The template to use
 //	data          The data to use to render the template
-func RenderDialogContents(template string, data map[string]string) string {
+//	theme         The theme the color helper uses
+//	partials      The partials the template can use
+func renderContents(template string, data map[string]string, theme string, partials map[string]string) (string, error) {
 	//
 	// Render the current for the first pass.
 	//
-	page, err := raymond.Render(template, data)
+	tpl, err := raymond.Parse(template)
 	if err != nil {
-		log.Fatal(err)
+		return "", err
+	}
+	tpl.RegisterPartials(partials)
+	tpl.RegisterHelpers(templateHelpers(theme))
+	page, err := tpl.Exec(data)
+	if err != nil {
+		return "", err
 	}
 
 	//
 	// Return the results.
 	//
-	return page
+	return page, nil
 }
 
-// Function:     getRequest
+// Function:     sendRequest
 //
-// Description:  This method will issue a get request with the data sent
+// Description:  This method will issue a request with the data sent
 //
 //	as json in the body.
 //
 // Inputs:
 //
+//	method     The http method to use
 //	url        The url to send the request
 //	data       An io.Reader pointing to a json string
-func getRequest(url string, data io.Reader) string {
-	client := &http.Client{}
-	req, err := http.NewRequest(http.MethodGet, url, data)
+//	headers    Pairs of header names and values to add
+func sendRequest(method string, url string, data io.Reader, headers ...string) string {
+	body, err := trySendRequest(method, url, data, headers...)
 	if err != nil {
 		// handle error
 		log.Fatal(err)
 	}
+	return body
+}
+
+// Function:     trySendRequest
+//
+// Description:  This method will issue a request with the data sent
+//
+//	as json in the body. Problems are given back instead of ending
+//	the program.
+//
+// Inputs:
+//
+//	method     The http method to use
+//	url        The url to send the request
+//	data       An io.Reader pointing to a json string
+//	headers    Pairs of header names and values to add
+func trySendRequest(method string, url string, data io.Reader, headers ...string) (string, error) {
+	client := &http.Client{}
+	req, err := http.NewRequest(method, url, data)
+	if err != nil {
+		return "", err
+	}
 
 	// set the request header Content-Type for json
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
 
-	resp, err2 := client.Do(req)
-	if err2 != nil {
-		// handle error
-		log.Fatal(err2)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
 	}
-	body, err3 := ioutil.ReadAll(resp.Body)
-	if err3 != nil {
-		log.Fatal(err3)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
 	}
-	resp.Body.Close()
-	return string(body)
+	return string(body), nil
+}
+
+// Function:     getRequest
+//
+// Description:  This method will issue a get request with the data sent
+//
+//	as json in the body.
+//
+// Inputs:
+//
+//	url        The url to send the request
+//	data       An io.Reader pointing to a json string
+func getRequest(url string, data io.Reader) string {
+	return sendRequest(http.MethodGet, url, data)
 }
 
 // Function:     putRequest
@@ -108,27 +170,7 @@ func getRequest(url string, data io.Reader) string {
 //	url        The url to send the request
 //	data       An io.Reader pointing to a json string
 func putRequest(url string, data io.Reader) string {
-	client := &http.Client{}
-	req, err := http.NewRequest(http.MethodPut, url, data)
-	if err != nil {
-		// handle error
-		log.Fatal(err)
-	}
-
-	// set the request header Content-Type for json
-	req.Header.Set("Content-Type", "application/json; charset=utf-8")
-
-	resp, err2 := client.Do(req)
-	if err2 != nil {
-		// handle error
-		log.Fatal(err2)
-	}
-	body, err3 := ioutil.ReadAll(resp.Body)
-	if err3 != nil {
-		log.Fatal(err3)
-	}
-	resp.Body.Close()
-	return string(body)
+	return sendRequest(http.MethodPut, url, data)
 }
 
 // Function:     fileExists
@@ -181,6 +223,8 @@ func Map(list []string, f func(string) string) []string {
 //
 // description: The structure for the bubbletea interface for building a dialog.
 var buildDialog ModalDialog // The dialog structure we need to build
+var buildWizard Wizard      // The finished pages when building a wizard
+var buildPageTitle string   // The title of the page being built
 
 type model struct {
 	savefile     string            // The file to save the structure
@@ -198,6 +242,7 @@ type model struct {
 	labelqueue   []int             // The queue of inputs for a label
 	inputqueue   []int             // The queue of inputs for a input
 	buttonqueue  []int             // The queue of inputs for a button
+	pagequeue    []int             // The queue of inputs for a wizard page
 	err          error             // this will contain any errors from the validators
 }
 
@@ -211,6 +256,14 @@ const (
 	id
 	value
 	forid
+	required
+	pattern
+	minvalue
+	maxvalue
+	minlength
+	maxlength
+	errormessage
+	submits
 )
 
 const (
@@ -233,7 +286,7 @@ func stringValidator(s string) error {
 }
 
 func initialModel(savefile string) model {
-	var inputs []textinput.Model = make([]textinput.Model, 4)
+	var inputs []textinput.Model = make([]textinput.Model, 12)
 	inputs[name] = textinput.New()
 	inputs[name].Placeholder = ""
 	inputs[name].CharLimit = 100
@@ -262,26 +315,52 @@ func initialModel(savefile string) model {
 	inputs[forid].Prompt = ""
 	inputs[forid].Validate = nameValidator
 
+	//
+	// The validation rules for an input are all optional.
+	//
+	for _, rule := range []int{required, pattern, minvalue, maxvalue, minlength, maxlength, errormessage, submits} {
+		inputs[rule] = textinput.New()
+		inputs[rule].Placeholder = ""
+		inputs[rule].CharLimit = 100
+		inputs[rule].Width = 102
+		inputs[rule].Prompt = ""
+		inputs[rule].Validate = stringValidator
+	}
+
 	return model{
 		// Our list of acctions
 		savefile:     savefile,
 		inputName:    "input",
 		inputchoice:  0,
-		orgItems:     []string{"Add Item", "Add Button", "Test", "Save"},
-		diagItems:    []string{"Add label", "Add Input", "Add Selection", "Add Selection Option", "Add Radio", "Add Checkbox", "Add Color", "Add Date", "Add Datetime", "Add Email", "Add Month", "Add Password", "Add Telephone", "Add Time", "Add Url", "Save"},
-		choices:      []string{"Add Item", "Add Button", "Test", "Save"},
+		orgItems:     []string{"Add Item", "Add Button", "Add Page", "Test", "Save"},
+		diagItems:    builderMenu(),
+		choices:      []string{"Add Item", "Add Button", "Add Page", "Test", "Save"},
 		cursor:       0,
 		state:        0,
 		inputs:       inputs,
 		currentQueue: []int{name, id, value, forid},
 		labelqueue:   []int{name, id, value, forid},
-		inputqueue:   []int{name, id, value},
-		buttonqueue:  []int{name, id, value},
+		inputqueue:   []int{name, id, value, required, pattern, minvalue, maxvalue, minlength, maxlength, errormessage},
+		buttonqueue:  []int{name, id, value, submits},
+		pagequeue:    []int{name},
 		focused:      0,
 		err:          nil,
 	}
 }
 
+// Function:     builderMenu
+//
+// Description:  This function creates the list of items the builder can add from
+//
+//	the supported modal types.
+func builderMenu() []string {
+	var items []string
+	for _, mt := range ModalTypes {
+		items = append(items, fmt.Sprintf("Add %s", mt.Label))
+	}
+	return append(items, "Save")
+}
+
 func (m model) Init() tea.Cmd {
 	return textinput.Blink
 }
@@ -289,25 +368,27 @@ func (m model) Init() tea.Cmd {
 // nextInput focuses the next input field
 func (m *model) nextInput() {
 	//
-	// Increment the focused item and wrap around if
+	// Increment the position in the queue and wrap around if
 	// too large.
 	//
-	m.focused = (m.focused + 1) % len(m.currentQueue)
+	pos := (indexOf(m.currentQueue, m.focused) + 1) % len(m.currentQueue)
+	m.focused = m.currentQueue[pos]
 }
 
 // prevInput focuses the previous input field
 func (m *model) prevInput() {
 	//
-	// Decrement the focused item.
+	// Decrement the position in the queue.
 	//
-	m.focused--
+	pos := indexOf(m.currentQueue, m.focused) - 1
 
 	//
 	// If less than zero, wrap around to the highest number.
 	//
-	if m.focused < 0 {
-		m.focused = len(m.currentQueue) - 1
+	if pos < 0 {
+		pos = len(m.currentQueue) - 1
 	}
+	m.focused = m.currentQueue[pos]
 }
 
 type testDialogFinish struct{ m model }
@@ -316,11 +397,30 @@ func (m model) testDialog() tea.Msg {
 	//
 	// Send the dialog to BulletinBoard
 	//
-	file, _ := json.MarshalIndent(buildDialog, "", " ")
-	putRequest("http://localhost:9697/api/modal", strings.NewReader(string(file)))
+	structure, endpoint := builtStructure()
+	file, _ := json.MarshalIndent(structure, "", " ")
+	putRequest(fmt.Sprintf("http://localhost:9697/api/%s", endpoint), strings.NewReader(string(file)))
 	return testDialogFinish{m}
 }
 
+// Function:     builtStructure
+//
+// Description:  This function gives the structure built so far and the endpoint for
+//
+//	it. Once a page has been added, it is a wizard with the current dialog
+//	as the last page.
+func builtStructure() (interface{}, string) {
+	if len(buildWizard.Pages) == 0 {
+		return buildDialog, "modal"
+	}
+	wizard := buildWizard
+	wizard.Pages = append([]WizardPage(nil), buildWizard.Pages...)
+	if len(buildDialog.Items) > 0 {
+		wizard.Pages = append(wizard.Pages, WizardPage{ModalDialog: buildDialog, Title: buildPageTitle})
+	}
+	return wizard, "wizard"
+}
+
 type makeItemFinishedMsg struct{ m model }
 
 func (m model) MakeItem() tea.Msg {
@@ -339,6 +439,12 @@ func (m model) MakeButton() tea.Msg {
 	return makeButtonFinishedMsg{m}
 }
 
+type makePageFinishedMsg struct{ m model }
+
+func (m model) MakePage() tea.Msg {
+	return makePageFinishedMsg{m}
+}
+
 type makeInputFinishedMsg struct{ m model }
 
 func (m model) MakeInput() tea.Msg {
@@ -370,12 +476,22 @@ func (m model) SaveInput() tea.Msg {
 		//
 		// Creating a Input
 		//
+		mt := ModalTypes[m.inputchoice]
+		if err := mt.validateDefault(m.inputs[value].Value()); err != nil {
+			//
+			// Stay on the form so the value can be fixed.
+			//
+			return errMsg(err)
+		}
 		var di DialogItem
-		di.ModelType = strings.ToLower(m.inputName)
+		di.ModelType = mt.Name
 		di.Name = m.inputs[name].Value()
 		di.Id = m.inputs[id].Value()
 		di.Value = m.inputs[value].Value()
 		di.For = ""
+		if err := m.setRules(&di); err != nil {
+			return errMsg(err)
+		}
 		m.resetInputs()
 		buildDialog.Items = append(buildDialog.Items, di)
 		break
@@ -388,10 +504,27 @@ func (m model) SaveInput() tea.Msg {
 		db.Name = m.inputs[name].Value()
 		db.Id = m.inputs[id].Value()
 		db.Action = m.inputs[value].Value()
+		if answer := m.inputs[submits].Value(); strings.TrimSpace(answer) != "" {
+			submit := isYes(answer)
+			db.Submit = &submit
+		}
 		m.resetInputs()
 		buildDialog.Buttons = append(buildDialog.Buttons, db)
 		break
 
+	case 8:
+		//
+		// Starting a new wizard page. The dialog built so far is the page before it.
+		// Without any items, the title is just given to the page being built.
+		//
+		if len(buildDialog.Items) > 0 {
+			buildWizard.Pages = append(buildWizard.Pages, WizardPage{ModalDialog: buildDialog, Title: buildPageTitle})
+			buildDialog = ModalDialog{}
+		}
+		buildPageTitle = m.inputs[name].Value()
+		m.resetInputs()
+		break
+
 	default:
 		break
 	}
@@ -402,15 +535,58 @@ func (m model) SaveInput() tea.Msg {
 	return labelInputFinishedMsg{m}
 }
 
+// Function:     setRules
+//
+// Description:  This method copies the validation rules from the inputs into the
+//
+//	dialog item and makes sure they can be used.
+//
+// Inputs:
+//
+//	di       The dialog item being created
+func (m model) setRules(di *DialogItem) error {
+	di.Required = isYes(m.inputs[required].Value())
+	di.Pattern = m.inputs[pattern].Value()
+	di.Min = strings.TrimSpace(m.inputs[minvalue].Value())
+	di.Max = strings.TrimSpace(m.inputs[maxvalue].Value())
+	for _, limit := range []struct {
+		input int
+		dest  *int
+	}{{minlength, &di.MinLength}, {maxlength, &di.MaxLength}} {
+		str := strings.TrimSpace(m.inputs[limit.input].Value())
+		if str == "" {
+			continue
+		}
+		num, err := strconv.Atoi(str)
+		if err != nil {
+			return fmt.Errorf("the length %q isn't a whole number", str)
+		}
+		*limit.dest = num
+	}
+	di.ErrorMessage = m.inputs[errormessage].Value()
+	return di.checkRules()
+}
+
+// Function:     isYes
+//
+// Description:  This function tells if the user answered yes to a y/n input.
+//
+// Inputs:
+//
+//	answer     The value of the input
+func isYes(answer string) bool {
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes", "true":
+		return true
+	}
+	return false
+}
+
 func (m model) resetInputs() {
-	m.inputs[name].Reset()
-	m.inputs[name].SetValue("")
-	m.inputs[id].Reset()
-	m.inputs[id].SetValue("")
-	m.inputs[forid].Reset()
-	m.inputs[forid].SetValue("")
-	m.inputs[value].Reset()
-	m.inputs[value].SetValue("")
+	for i := range m.inputs {
+		m.inputs[i].Reset()
+		m.inputs[i].SetValue("")
+	}
 }
 
 type saveSturctureFinishedMsg struct{ m model }
@@ -419,9 +595,19 @@ func (m model) SaveStructure() tea.Msg {
 	//
 	// Save the structure to a file.
 	//
-	file, _ := json.MarshalIndent(buildDialog, "", " ")
-	header := "# This a dialog created by the builder.\n"
-	_ = ioutil.WriteFile(m.savefile, []byte(header+string(file)), 0644)
+	structure, format := builtStructure()
+	file, _ := json.MarshalIndent(structure, "", " ")
+	header := TemplateMeta{
+		Name:        FilenameWithoutExtension(filepath.Base(m.savefile)),
+		Description: "This a dialog created by the builder.",
+		Format:      format,
+	}.header()
+	if err := writeTemplate(m.savefile, []byte(header+string(file))); err != nil {
+		//
+		// Stay in the builder so the dialog isn't lost. Saving can be tried again.
+		//
+		return errMsg(fmt.Errorf("the dialog can't be saved to %s: %w", m.savefile, err))
+	}
 	return saveSturctureFinishedMsg{m}
 }
 
@@ -447,6 +633,7 @@ func switchInQueryMode(m model, msg string) (tea.Model, tea.Cmd) {
 
 	// The "enter" key will select the action to perform.
 	case "enter":
+		m.err = nil
 		switch m.state {
 		case 0:
 			if m.cursor == 0 {
@@ -454,6 +641,8 @@ func switchInQueryMode(m model, msg string) (tea.Model, tea.Cmd) {
 			} else if m.cursor == 1 {
 				return m, m.MakeButton
 			} else if m.cursor == 2 {
+				return m, m.MakePage
+			} else if m.cursor == 3 {
 				return m, m.testDialog
 			} else {
 				// this would save.
@@ -461,62 +650,16 @@ func switchInQueryMode(m model, msg string) (tea.Model, tea.Cmd) {
 			}
 
 		case 1:
-			if m.cursor == 0 {
-				return m, m.MakeLabel
-			} else if m.cursor == 15 {
+			if m.cursor == len(m.diagItems)-1 {
 				// This would save.
 				return m, m.SaveStructure
+			} else if ModalTypes[m.cursor].Name == "label" {
+				return m, m.MakeLabel
 			} else {
 				//
 				// Make this generic for one of the many input types.
 				//
-				switch m.cursor {
-				case 1:
-					m.inputName = "Input"
-					break
-				case 2:
-					m.inputName = "Selection"
-					break
-				case 3:
-					m.inputName = "Option"
-					break
-				case 4:
-					m.inputName = "Radio"
-					break
-				case 5:
-					m.inputName = "Checkbox"
-					break
-				case 6:
-					m.inputName = "Color"
-					break
-				case 7:
-					m.inputName = "Date"
-					break
-				case 8:
-					m.inputName = "Datetime"
-					break
-				case 9:
-					m.inputName = "Email"
-					break
-				case 10:
-					m.inputName = "Month"
-					break
-				case 11:
-					m.inputName = "Password"
-					break
-				case 12:
-					m.inputName = "Telephone"
-					break
-				case 13:
-					m.inputName = "Time"
-					break
-				case 14:
-					m.inputName = "Url"
-					break
-				case 15:
-					m.inputName = "Week"
-					break
-				}
+				m.inputName = ModalTypes[m.cursor].Label
 				return m, m.MakeInput
 			}
 
@@ -534,7 +677,7 @@ func switchInLabelMode(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.Type {
 		case tea.KeyEnter:
-			if m.focused == len(m.currentQueue)-1 {
+			if m.focused == m.currentQueue[len(m.currentQueue)-1] {
 				//
 				// This is the last input, save the inputs
 				//
@@ -582,12 +725,17 @@ func switchInLabelMode(m model, msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // Checking for item inside of an array.
 func contains(a []int, item int) bool {
-	for _, v := range a {
+	return indexOf(a, item) >= 0
+}
+
+// Finding the position of an item inside of an array. It is -1 if not there.
+func indexOf(a []int, item int) int {
+	for i, v := range a {
 		if v == item {
-			return true
+			return i
 		}
 	}
-	return false
+	return -1
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -616,6 +764,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.cursor = 0
 		m.state = 0
 		m.focused = name
+		m.err = nil
+		return m, nil
+
+	case errMsg:
+		m.err = msg2
 		return m, nil
 
 	case makeInputFinishedMsg:
@@ -627,6 +780,14 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.focused = name
 		return m, nil
 
+	case makePageFinishedMsg:
+		m.choices = m.orgItems
+		m.cursor = 0
+		m.state = 8
+		m.currentQueue = m.pagequeue
+		m.focused = name
+		return m, nil
+
 	case makeButtonFinishedMsg:
 		m.choices = m.orgItems
 		m.cursor = 0
@@ -643,7 +804,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch m.state {
 		case 0, 1, 3, 5:
 			return switchInQueryMode(m, msg2.String())
-		case 2, 4, 6:
+		case 2, 4, 6, 8:
 			return switchInLabelMode(m, msg)
 		}
 	}
@@ -667,6 +828,10 @@ func viewChoices(m model) string {
 		s += fmt.Sprintf("%s %s\n", cursor, choice)
 	}
 
+	if m.err != nil {
+		s += fmt.Sprintf("\n Error: %s\n", m.err)
+	}
+
 	// The footer
 	s += "\nPress j to move down. Press k to move up. Press enter to select. Press q to quit.\n\n\n\n"
 
@@ -701,6 +866,14 @@ func viewLabelInputs(m model) string {
 }
 
 func viewInputInputs(m model) string {
+	valueLabel := "Default Value"
+	if format := ModalTypes[m.inputchoice].Format; format != "" {
+		valueLabel = fmt.Sprintf("Default Value (%s)", format)
+	}
+	errLine := ""
+	if m.err != nil {
+		errLine = fmt.Sprintf(" Error: %s\n", m.err)
+	}
 	return fmt.Sprintf(
 		` Fields for the %s
 
@@ -717,10 +890,47 @@ func viewInputInputs(m model) string {
 		m.inputs[name].View(),
 		inputStyle.Width(2).Render("ID"),
 		m.inputs[id].View(),
-		inputStyle.Width(13).Render("Default Value"),
+		inputStyle.Width(len(valueLabel)).Render(valueLabel),
 		m.inputs[value].View(),
+		continueStyle.Render("Validation (all optional) ->"),
+	) + viewRuleInputs(m) + errLine + "\n"
+}
+
+func viewRuleInputs(m model) string {
+	return fmt.Sprintf(
+		`
+ %s
+ %s
+ %s
+ %s
+ %s
+ %s
+ %s
+ %s
+ %s
+ %s
+ %s
+ %s
+ %s
+ %s
+ %s
+`,
+		inputStyle.Width(16).Render("Required (y/n)"),
+		m.inputs[required].View(),
+		inputStyle.Width(7).Render("Pattern"),
+		m.inputs[pattern].View(),
+		inputStyle.Width(3).Render("Min"),
+		m.inputs[minvalue].View(),
+		inputStyle.Width(3).Render("Max"),
+		m.inputs[maxvalue].View(),
+		inputStyle.Width(10).Render("Min Length"),
+		m.inputs[minlength].View(),
+		inputStyle.Width(10).Render("Max Length"),
+		m.inputs[maxlength].View(),
+		inputStyle.Width(13).Render("Error Message"),
+		m.inputs[errormessage].View(),
 		continueStyle.Render("Continue ->"),
-	) + "\n"
+	)
 }
 
 func viewButtonInputs(m model) string {
@@ -733,14 +943,32 @@ func viewButtonInputs(m model) string {
  %s
  %s  
  %s
+ %s
+ %s
  %s  
 `,
 		inputStyle.Width(11).Render("Button Name"),
 		m.inputs[name].View(),
 		inputStyle.Width(2).Render("ID"),
 		m.inputs[id].View(),
-		inputStyle.Width(13).Render("Action"),
+		inputStyle.Width(47).Render("Action (submit, cancel, or your own action id)"),
 		m.inputs[value].View(),
+		inputStyle.Width(41).Render("Submits Values (y/n, blank for default)"),
+		m.inputs[submits].View(),
+		continueStyle.Render("Continue ->"),
+	) + "\n"
+}
+
+func viewPageInputs(m model) string {
+	return fmt.Sprintf(
+		` Start a New Wizard Page
+
+ %s
+ %s
+ %s  
+`,
+		inputStyle.Width(10).Render("Page Title"),
+		m.inputs[name].View(),
 		continueStyle.Render("Continue ->"),
 	) + "\n"
 }
@@ -766,6 +994,9 @@ func (m model) View() string {
 	case 6:
 		result = viewButtonInputs(m)
 		break
+	case 8:
+		result = viewPageInputs(m)
+		break
 	}
 
 	//
@@ -808,21 +1039,15 @@ func isDirectory(path string) (bool, error) {
 //
 // Inputs:
 //
-//	              The inputs are assigned to os.Argv. It should be a dialog
-//	              name and the data to use to expand it. Currently made for the
-//					       macOS.
-//
-// #TODO: make to work for other Oses.
+//	The inputs are assigned to os.Argv. It should be a dialog
+//	name and the data to use to expand it.
 func main() {
 	//
-	// Get the two template locations.
+	// Get the user's template and theme locations. The others are found with
+	// resourceDirs when they are needed.
 	//
-	home := os.Getenv("HOME")
-	progHome, _ := os.Executable()
-	progHome = filepath.Dir(progHome)
-	templates1 := filepath.Join(progHome, "../Resources/dialogs") // Installation premade templates. Macos only. TODO: make more generic for other oses.
-	templates2 := filepath.Join(home, ".config/bulletinboard/dialogs")
-	themeDir := filepath.Join(home, ".config/bulletinboard/themes")
+	templates2 := userDir("dialogs")
+	themeDir := userDir("themes")
 
 	//
 	// Make sure the directory exists and is setup for use.
@@ -869,7 +1094,7 @@ func main() {
 		},
 		Copyright: "(c) 2022 Richard Guay",
 		HelpName:  "bbmsg",
-		UsageText: "build <name>\nlist\nsend message|template <data1> <data2>...",
+		UsageText: "build <name>\nlist\nsend message|template <data1> <data2>...\nhistory clear <name>\nlog [--since] [--template] [--tail]",
 		Action: func(cCtx *cli.Context) error {
 			if cCtx.Args().Len() == 0 {
 				//
@@ -910,8 +1135,24 @@ func main() {
 				Name:    "list",
 				Aliases: []string{"l"},
 				Usage:   "List all templates available",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Give every copy of each template with where it is, its format, and if it is shadowed",
+					},
+					&cli.BoolFlag{
+						Name:  "table",
+						Usage: "Show every copy of each template as a table",
+					},
+				},
 				Action: func(cCtx *cli.Context) error {
-					listTemplates(templates1, templates2)
+					format := ""
+					if cCtx.Bool("json") {
+						format = "json"
+					} else if cCtx.Bool("table") {
+						format = "table"
+					}
+					listTemplates(templateLayers(), format)
 					return nil
 				},
 			},
@@ -926,7 +1167,7 @@ func main() {
 						Usage:   "Send a template to the BulletinBoard",
 						Action: func(cCtx *cli.Context) error {
 							if cCtx.Args().Len() > 0 {
-								sendTemplate(templates1, templates2, cCtx.Args().Get(0), cCtx.Args())
+								sendTemplate(templateLayers(), cCtx.Args().Get(0), cCtx.Args())
 							} else {
 								fmt.Print("You didn't give a template name.")
 							}
@@ -937,9 +1178,51 @@ func main() {
 						Name:    "message",
 						Aliases: []string{"m"},
 						Usage:   "Send a message to the BulletinBoard",
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{
+								Name:    "button",
+								Aliases: []string{"b"},
+								Usage:   "Ask a question with a button given as id or id:Label. The id of the button pressed is returned.",
+							},
+							&cli.StringFlag{
+								Name:    "level",
+								Aliases: []string{"l"},
+								Usage:   "The level of the message: info, success, warning, or error",
+							},
+							&cli.StringFlag{
+								Name:    "title",
+								Aliases: []string{"t"},
+								Usage:   "A title to show above the message",
+							},
+							&cli.IntFlag{
+								Name:  "ttl",
+								Usage: "The number of seconds to show the message before hiding it",
+							},
+							&cli.BoolFlag{
+								Name:    "markdown",
+								Aliases: []string{"md"},
+								Usage:   "Show the message as markdown",
+							},
+						},
 						Action: func(cCtx *cli.Context) error {
-							if cCtx.Args().Len() > 0 {
-								sendMessage(cCtx.Args().Get(0))
+							if cCtx.Args().Len() > 0 && len(cCtx.StringSlice("button")) > 0 {
+								sendPrompt(cCtx.Args().Get(0), cCtx.StringSlice("button"))
+							} else if cCtx.Args().Len() > 0 {
+								level, err := checkLevel(cCtx.String("level"))
+								if err != nil {
+									return err
+								}
+								format := "text"
+								if cCtx.Bool("markdown") {
+									format = "markdown"
+								}
+								sendMessage(Msg{
+									Message: cCtx.Args().Get(0),
+									Title:   cCtx.String("title"),
+									Level:   level,
+									TTL:     cCtx.Int("ttl"),
+									Format:  format,
+								})
 							} else {
 								fmt.Print("You didn't give a message!")
 							}
@@ -948,6 +1231,343 @@ func main() {
 					},
 				},
 			},
+			{
+				Name:  "template",
+				Usage: "Work with the templates",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "copy",
+						Usage:     "Copy a template, like a built-in one, into your templates under a new name",
+						ArgsUsage: "<template> <newname>",
+						Action: func(cCtx *cli.Context) error {
+							if cCtx.Args().Len() < 2 {
+								return fmt.Errorf("you need to give the template to copy and the new name")
+							}
+							if err := copyTemplate(cCtx.Args().Get(0), cCtx.Args().Get(1)); err != nil {
+								return err
+							}
+							fmt.Printf("Copied %s to %s.\n", cCtx.Args().Get(0), cCtx.Args().Get(1))
+							return nil
+						},
+					},
+					{
+						Name:      "export",
+						Usage:     "Put templates with the themes and assets they use into a bundle",
+						ArgsUsage: "<template>...",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    "output",
+								Aliases: []string{"o"},
+								Value:   "templates.tar.gz",
+								Usage:   "The bundle file to write",
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							names, flags := trailingFlags(cCtx.Args().Slice(), []string{"o", "output"}, nil)
+							output := cCtx.String("output")
+							if flags["output"] != "" {
+								output = flags["output"]
+							}
+							if err := exportTemplates(names, output); err != nil {
+								return err
+							}
+							fmt.Printf("Exported %s to %s.\n", strings.Join(names, ", "), output)
+							return nil
+						},
+					},
+					{
+						Name:      "import",
+						Usage:     "Add the templates, themes, and assets of a bundle to yours",
+						ArgsUsage: "<bundle>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "prefix",
+								Usage: "Put this in front of the names of the templates and themes",
+							},
+							&cli.BoolFlag{
+								Name:  "force",
+								Usage: "Write over templates, themes, and assets that are already there",
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							args, flags := trailingFlags(cCtx.Args().Slice(), []string{"prefix"}, []string{"force"})
+							if len(args) == 0 {
+								return fmt.Errorf("you didn't give the bundle to import")
+							}
+							prefix := cCtx.String("prefix")
+							if flags["prefix"] != "" {
+								prefix = flags["prefix"]
+							}
+							imported, err := importTemplates(args[0], prefix, cCtx.Bool("force") || flags["force"] != "")
+							if err != nil {
+								return err
+							}
+							fmt.Printf("Imported %s. Raw templates can't run their scripts until you use bb template trust.\n", strings.Join(imported, ", "))
+							return nil
+						},
+					},
+					{
+						Name:      "trust",
+						Usage:     "Let one of your templates, like an imported one, run its scripts",
+						ArgsUsage: "<template>",
+						Action: func(cCtx *cli.Context) error {
+							if cCtx.Args().Len() == 0 {
+								return fmt.Errorf("you didn't give a template name")
+							}
+							if err := trustTemplate(cCtx.Args().Get(0)); err != nil {
+								return err
+							}
+							fmt.Printf("%s can run its scripts now.\n", cCtx.Args().Get(0))
+							return nil
+						},
+					},
+					{
+						Name:      "history",
+						Usage:     "List the revisions kept of one of your templates, newest first",
+						ArgsUsage: "<template>",
+						Action: func(cCtx *cli.Context) error {
+							if cCtx.Args().Len() == 0 {
+								return fmt.Errorf("you didn't give a template name")
+							}
+							return listRevisions(cCtx.Args().Get(0))
+						},
+					},
+					{
+						Name:      "diff",
+						Usage:     "Show the changes from a revision of a template to what it is now",
+						ArgsUsage: "<template> <rev>",
+						Action: func(cCtx *cli.Context) error {
+							if cCtx.Args().Len() < 2 {
+								return fmt.Errorf("you need to give the template and the revision")
+							}
+							return diffRevision(cCtx.Args().Get(0), cCtx.Args().Get(1))
+						},
+					},
+					{
+						Name:      "restore",
+						Usage:     "Put a revision of a template back. What is there now is kept as a revision.",
+						ArgsUsage: "<template> <rev>",
+						Action: func(cCtx *cli.Context) error {
+							if cCtx.Args().Len() < 2 {
+								return fmt.Errorf("you need to give the template and the revision")
+							}
+							revision, err := restoreRevision(cCtx.Args().Get(0), cCtx.Args().Get(1))
+							if err != nil {
+								return err
+							}
+							fmt.Printf("Restored %s to revision %s.\n", cCtx.Args().Get(0), revision.Id)
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:      "render",
+				Usage:     "Show what bb send template would send without sending it",
+				ArgsUsage: "<template> [data...]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "payload",
+						Usage: "Only print the json that would be sent",
+					},
+				},
+				Action: func(cCtx *cli.Context) error {
+					args, flags := trailingFlags(cCtx.Args().Slice(), nil, []string{"payload"})
+					if len(args) == 0 {
+						return fmt.Errorf("you didn't give a template name")
+					}
+					return printRender(args[0], args[1:], cCtx.Bool("payload") || flags["payload"] != "")
+				},
+			},
+			{
+				Name:      "dev",
+				Usage:     "Send a template again each time it, a partial, or a theme is saved",
+				ArgsUsage: "<template> [data...]",
+				Action: func(cCtx *cli.Context) error {
+					if cCtx.Args().Len() == 0 {
+						return fmt.Errorf("you didn't give a template name")
+					}
+					return devTemplate(cCtx.Args().Get(0), cCtx.Args().Slice()[1:])
+				},
+			},
+			{
+				Name:      "describe",
+				Usage:     "Show what a template is for and the values it takes",
+				ArgsUsage: "<template>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Give the description as json",
+					},
+				},
+				Action: func(cCtx *cli.Context) error {
+					if cCtx.Args().Len() == 0 {
+						return fmt.Errorf("you didn't give a template name")
+					}
+					return describeTemplate(cCtx.Args().Get(0), cCtx.Bool("json"))
+				},
+			},
+			{
+				Name:  "messages",
+				Usage: "Show the messages sent to the BulletinBoard, oldest first",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "Only show the last number of messages",
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Give the messages as json",
+					},
+				},
+				Action: func(cCtx *cli.Context) error {
+					listMessages(cCtx.Int("limit"), cCtx.Bool("json"))
+					return nil
+				},
+			},
+			{
+				Name:  "progress",
+				Usage: "Show a progress bar for a long running script",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "start",
+						Usage: "Start a progress bar and print its id",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    "title",
+								Aliases: []string{"t"},
+								Usage:   "The title to show above the bar",
+							},
+							&cli.IntFlag{
+								Name:  "total",
+								Usage: "The count the work is done at. Leave it out to just show the count.",
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							bar, err := startProgress(cCtx.String("title"), cCtx.Int("total"))
+							if err != nil {
+								return err
+							}
+							fmt.Println(bar.Id)
+							return nil
+						},
+					},
+					{
+						Name:      "update",
+						Usage:     "Update the count or status of a progress bar",
+						ArgsUsage: "<id>",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:    "current",
+								Aliases: []string{"c"},
+								Usage:   "The count done so far",
+							},
+							&cli.StringFlag{
+								Name:    "status",
+								Aliases: []string{"s"},
+								Usage:   "A line of text to show under the bar",
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							if cCtx.Args().Len() == 0 {
+								return fmt.Errorf("you didn't give the id of the progress bar")
+							}
+							var update ProgressUpdate
+							if cCtx.IsSet("current") {
+								current := cCtx.Int("current")
+								update.Current = &current
+							}
+							if cCtx.IsSet("status") {
+								status := cCtx.String("status")
+								update.Status = &status
+							}
+							return updateProgress(cCtx.Args().Get(0), update)
+						},
+					},
+					{
+						Name:      "done",
+						Usage:     "Finish a progress bar and take it down",
+						ArgsUsage: "<id>",
+						Action: func(cCtx *cli.Context) error {
+							if cCtx.Args().Len() == 0 {
+								return fmt.Errorf("you didn't give the id of the progress bar")
+							}
+							return doneProgress(cCtx.Args().Get(0))
+						},
+					},
+					{
+						Name:  "pipe",
+						Usage: "Count the lines of stdin on a progress bar while passing them to stdout",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    "title",
+								Aliases: []string{"t"},
+								Usage:   "The title to show above the bar",
+							},
+							&cli.IntFlag{
+								Name:  "total",
+								Usage: "The number of lines expected. Leave it out to just show the count.",
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							return pipeProgress(cCtx.String("title"), cCtx.Int("total"), os.Stdin, os.Stdout)
+						},
+					},
+				},
+			},
+			{
+				Name:  "log",
+				Usage: "Show the audit log of the dialogs asked and answered",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "since",
+						Usage: "Only show entries since a duration ago (2h), a date (2024-05-01), or a time",
+					},
+					&cli.StringFlag{
+						Name:  "template",
+						Usage: "Only show entries for a template",
+					},
+					&cli.IntFlag{
+						Name:  "tail",
+						Usage: "Only show the last number of entries",
+					},
+				},
+				Action: func(cCtx *cli.Context) error {
+					var since time.Time
+					if cCtx.String("since") != "" {
+						var err error
+						since, err = parseSince(cCtx.String("since"))
+						if err != nil {
+							fmt.Print(err)
+							return nil
+						}
+					}
+					if err := queryAudit(since, cCtx.String("template"), cCtx.Int("tail")); err != nil {
+						fmt.Print(err)
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "history",
+				Usage: "Work with the answers remembered for templates",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "clear",
+						Usage: "Forget the answers remembered for a template",
+						Action: func(cCtx *cli.Context) error {
+							if cCtx.Args().Len() > 0 {
+								if err := clearAnswers(cCtx.Args().Get(0)); err != nil {
+									fmt.Print(err)
+								}
+							} else {
+								fmt.Print("Error: you didn't give a template name.")
+							}
+							return nil
+						},
+					},
+				},
+			},
 			{
 				Name:    "theme",
 				Aliases: []string{"thm"},
@@ -972,7 +1592,7 @@ func main() {
 						Usage:   "Load a theme.",
 						Action: func(cCtx *cli.Context) error {
 							if cCtx.Args().Len() > 0 {
-								loadTheme(themeDir, cCtx.Args().Get(0))
+								loadTheme(cCtx.Args().Get(0))
 							} else {
 								fmt.Print("Error: You didn't give a name!")
 							}
@@ -997,7 +1617,7 @@ func main() {
 						Aliases: []string{"listthm"},
 						Usage:   "List the available themes.",
 						Action: func(ctx *cli.Context) error {
-							listThemes(themeDir)
+							listThemes(resourceDirs("themes"))
 							return nil
 						},
 					},
@@ -1011,17 +1631,22 @@ func main() {
 	}
 }
 
-func listThemes(themeDir string) {
+func listThemes(themeDirs []string) {
 	//
-	// Give the user a json list of themes in the user directories.
+	// Give the user a json list of themes in the theme directories.
 	//
 	var nlist []string
-	file, _ := os.Open(themeDir)
-	dlist, _ := file.Readdirnames(0) // 0 to read all files and folders
-	for _, name := range dlist {
-		nlist = append(nlist, name)
+	for _, themeDir := range themeDirs {
+		file, err := os.Open(themeDir)
+		if err != nil {
+			continue
+		}
+		dlist, _ := file.Readdirnames(0) // 0 to read all files and folders
+		for _, name := range dlist {
+			nlist = append(nlist, name)
+		}
+		file.Close()
 	}
-	file.Close()
 	nlist = Map(nlist, FilenameWithoutExtension)
 	pjson, err := json.Marshal(nlist)
 	if err != nil {
@@ -1043,20 +1668,40 @@ func deleteTheme(themeDir string, theme string) {
 }
 
 func deleteTemplate(templateDir string, template string) {
-	templatepath := filepath.Join(templateDir, fmt.Sprintf("%s.json", template))
-	if fileExists(templatepath) {
+	file := (templateLayer{Dir: templateDir, Files: os.DirFS(templateDir)}).file(template)
+	if file != "" {
 		//
-		// It exists, so remove it.
+		// It exists, so remove it. The html file of a yaml or toml template goes
+		// with it. Both are kept as revisions so bb template restore can bring
+		// them back.
 		//
-		os.Remove(templatepath)
+		templatepath := filepath.Join(templateDir, file)
+		removed := []string{templatepath}
+		if templateKind(file) != "json" {
+			removed = append(removed, filepath.Join(templateDir, template+".html"))
+		}
+		for _, path := range removed {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			if err := saveRevision(path, data); err != nil {
+				fmt.Printf("The template, %s, wasn't deleted since it couldn't be kept as a revision: %v", template, err)
+				return
+			}
+			os.Remove(path)
+		}
+		if err := setTemplateTrust([]string{template}, true); err != nil {
+			fmt.Printf("The template, %s, was deleted, but it is still listed as untrusted: %v", template, err)
+		}
 	} else {
 		fmt.Printf("The template, %s, doesn't exist.", template)
 	}
 }
 
-func loadTheme(themeDir string, theme string) {
-	themefile := filepath.Join(themeDir, fmt.Sprintf("%s.json", theme))
-	if fileExists(themefile) {
+func loadTheme(theme string) {
+	themefile := findResource("themes", fmt.Sprintf("%s.json", theme))
+	if themefile != "" {
 		themestr, err := os.ReadFile(themefile)
 		if err != nil {
 			//
@@ -1083,41 +1728,178 @@ func createTheme(themeDir string, theme string) {
 	}
 }
 
-func sendMessage(msg string) {
+func sendMessage(msg Msg) {
 	//
-	// Send the message given to teh BulletinBoard.
+	// Send the message given to teh BulletinBoard. The message, title, level, and ttl
+	// all go in the body so the message can be as long as needed.
 	//
-	encmsg := url.QueryEscape(msg)
-	urimsg := fmt.Sprintf("http://localhost:9697/api/message/%s", encmsg)
-	bodyStr := fmt.Sprintf("{\"msg\": \"%s\"}", encmsg)
-	result := getRequest(urimsg, strings.NewReader(bodyStr))
+	body, _ := json.Marshal(msg)
+	result := sendRequest(http.MethodPost, "http://localhost:9697/api/message", strings.NewReader(string(body)))
 	fmt.Printf("%s", result[1:len(result)-1])
 }
 
-func listTemplates(templates1 string, templates2 string) {
-	//
-	// Give the user a json list of dialogs in the program
-	// area and in the user directory.
-	//
-	var nlist []string
-	file, _ := os.Open(templates1)
-	dlist, _ := file.Readdirnames(0) // 0 to read all files and folders
-	for _, name := range dlist {
-		nlist = append(nlist, name)
-	}
-	file.Close()
-	file, _ = os.Open(templates2)
-	dlist, _ = file.Readdirnames(0)
-	for _, name := range dlist {
-		nlist = append(nlist, name)
-	}
-	file.Close()
-	nlist = Map(nlist, FilenameWithoutExtension)
-	pjson, err := json.Marshal(nlist)
-	if err != nil {
-		log.Fatal("Cannot encode to JSON ", err)
+// Function:     trailingFlags
+//
+// Description:  This function picks out the flags given after the arguments, like
+//
+//	bb template export a b -o pack.tar.gz. The cli stops reading flags at the
+//	first argument. The first name of a flag is an alias for the last one.
+//
+// Inputs:
+//
+//	args       The arguments given
+//	valued     The names of the flags that take a value
+//	switches   The names of the flags that don't
+func trailingFlags(args []string, valued []string, switches []string) ([]string, map[string]string) {
+	flags := make(map[string]string)
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") {
+			rest = append(rest, args[i])
+			continue
+		}
+		matched := false
+		for _, flag := range valued {
+			if name == flag {
+				if !hasValue && i+1 < len(args) {
+					i++
+					value = args[i]
+				}
+				flags[valued[len(valued)-1]] = value
+				matched = true
+			}
+		}
+		for _, flag := range switches {
+			if name == flag {
+				flags[flag] = "true"
+				matched = true
+			}
+		}
+		if !matched {
+			rest = append(rest, args[i])
+		}
+	}
+	return rest, flags
+}
+
+// Function:     listMessages
+//
+// Description:  This function prints the history of messages the BulletinBoard has
+//
+//	been sent. Each is on a line with the time, level, and title unless json
+//	is asked for.
+//
+// Inputs:
+//
+//	limit      The number of the newest messages to show. Zero shows them all.
+//	asJson     True to print the json given by the BulletinBoard
+func listMessages(limit int, asJson bool) {
+	result := getRequest(fmt.Sprintf("http://localhost:9697/api/messages?limit=%d", limit), nil)
+	if asJson {
+		fmt.Println(result)
+		return
+	}
+	var history struct {
+		Messages []MessageRecord `json:"messages"`
+		Error    string          `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(result), &history); err != nil {
+		fmt.Print(result)
+		return
+	}
+	if history.Error != "" {
+		fmt.Print(history.Error)
+		return
+	}
+	for _, record := range history.Messages {
+		line := fmt.Sprintf("%s [%s]", record.Time.Local().Format("2006-01-02 15:04:05"), record.Level)
+		if record.Title != "" {
+			line += " " + record.Title + ":"
+		}
+		fmt.Println(line, record.Message)
+	}
+}
+
+// Function:     sendPrompt
+//
+// Description:  This function sends a message with buttons as a modal dialog. Each
+//
+//	button is given as id or id:Label and uses its id as the action. A button
+//	with the id cancel gives a canceled status.
+//
+// Inputs:
+//
+//	msg        The message to show
+//	buttons    The buttons to show
+func sendPrompt(msg string, buttons []string) {
+	var prompt ModalDialog
+	prompt.Items = []DialogItem{
+		{ModelType: "label", Name: "message", Id: "message", Value: msg},
+	}
+	for _, button := range buttons {
+		id, label, found := strings.Cut(button, ":")
+		if !found {
+			label = id
+		}
+		prompt.Buttons = append(prompt.Buttons, DialogButton{Name: label, Id: id, Action: id})
+	}
+	file, _ := json.Marshal(prompt)
+	result := putRequest("http://localhost:9697/api/modal", strings.NewReader(string(file)))
+	fmt.Printf("%s", result)
+}
+
+// Function:     listTemplates
+//
+// Description:  This function lists the templates in the template directories and
+//
+//	the ones built into the program. Without a format, it gives the json list
+//	of the names that can be sent. The json format tells about every copy of
+//	each template and the table format shows the same for people.
+//
+// Inputs:
+//
+//	layers     The places to look
+//	format     "json", "table", or "" for the list of names
+func listTemplates(layers []templateLayer, format string) {
+	list := templateInfo(layers)
+	switch format {
+	case "json":
+		pjson, err := json.MarshalIndent(list, "", " ")
+		if err != nil {
+			log.Fatal("Cannot encode to JSON ", err)
+		}
+		fmt.Println(string(pjson))
+
+	case "table":
+		table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "NAME\tFORMAT\tSOURCE\tDESCRIPTION")
+		for _, info := range list {
+			name := info.Name
+			if info.Shadowed {
+				name += " (shadowed)"
+			}
+			desc := info.Description
+			if info.Error != "" {
+				desc = info.Error
+			}
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", name, info.Format, info.Source, desc)
+		}
+		table.Flush()
+
+	default:
+		nlist := []string{}
+		for _, info := range list {
+			if !info.Shadowed {
+				nlist = append(nlist, info.Name)
+			}
+		}
+		pjson, err := json.Marshal(nlist)
+		if err != nil {
+			log.Fatal("Cannot encode to JSON ", err)
+		}
+		fmt.Printf("{ \"dialogs\": %s}\n", pjson)
 	}
-	fmt.Printf("{ \"dialogs\": %s}\n", pjson)
 }
 
 func buildTUI(templatesDir string, name string) {
@@ -1143,60 +1925,139 @@ func buildTUI(templatesDir string, name string) {
 	}
 }
 
-func sendTemplate(templates1 string, templates2 string, dialog string, dt cli.Args) {
-	//
-	// Create the data structure for the command line data.
-	//
-	data := make(map[string]string, dt.Len())
+// Function:     isWizard
+//
+// Description:  This function tells if a json structure dialog is a wizard. Wizards
+//
+//	have a list of pages in place of the items.
+//
+// Inputs:
+//
+//	jsonStr     The json for the dialog
+func isWizard(jsonStr string) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(jsonStr), &fields); err != nil {
+		return false
+	}
+	_, ok := fields["pages"]
+	return ok
+}
 
-	//
-	// Create the rest of the command line into the data needed for the dialog template.
-	//
-	for i := 1; i < dt.Len(); i++ {
-		data[fmt.Sprintf("data%d", i)] = dt.Get(i)
+// Function:     prepareModalTemplate
+//
+// Description:  This function gets a json structure dialog ready to send. The options
+//
+//	of selections with an optionsFrom are filled in. Then the item types,
+//	validation rules, and the ids used by the conditions are checked.
+//
+// Inputs:
+//
+//	jsonStr     The json for the dialog
+//	name        The name of the template. It names the remembered answers.
+func prepareModalTemplate(jsonStr string, name string) (string, error) {
+	var structure interface{}
+	if isWizard(jsonStr) {
+		var wizard Wizard
+		if err := json.Unmarshal([]byte(jsonStr), &wizard); err != nil {
+			return "", err
+		}
+		for i := range wizard.Pages {
+			if err := resolveOptions(&wizard.Pages[i].ModalDialog); err != nil {
+				return "", err
+			}
+		}
+		if err := normalizeWizard(&wizard); err != nil {
+			return "", err
+		}
+		if wizard.Name == "" {
+			wizard.Name = name
+		}
+		structure = wizard
+	} else {
+		var dialog ModalDialog
+		if err := json.Unmarshal([]byte(jsonStr), &dialog); err != nil {
+			return "", err
+		}
+		if err := resolveOptions(&dialog); err != nil {
+			return "", err
+		}
+		if err := normalizeModalDialog(&dialog); err != nil {
+			return "", err
+		}
+		if dialog.Name == "" {
+			dialog.Name = name
+		}
+		structure = dialog
+	}
+	result, err := json.Marshal(structure)
+	return string(result), err
+}
+
+// Function:     nameDialog
+//
+// Description:  This function adds the template name to a raw html dialog so that it
+//
+//	shows in the audit log. A dialog that names itself or isn't valid json is
+//	left as it is.
+//
+// Inputs:
+//
+//	jsonStr     The json for the dialog
+//	name        The name of the template
+func nameDialog(jsonStr string, name string) string {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonStr), &fields); err != nil {
+		return jsonStr
+	}
+	if _, ok := fields["name"]; ok {
+		return jsonStr
 	}
+	fields["name"] = name
+	named, err := json.Marshal(fields)
+	if err != nil {
+		return jsonStr
+	}
+	return string(named)
+}
 
+func sendTemplate(layers []templateLayer, dialog string, dt cli.Args) {
 	//
 	// Create an error dialog if the dialog can't be found.
 	//
 	var jsonStr string = "{ \"html\": \"<h1>Dialog not found.<h1>\", \"width\": 100, \"height\": 200, \"x\": 200, \"y\": 200}"
+	file := dialog + ".json"
+	trusted := false
 
 	//
-	// Create the two possible file locations.
+	// Use the first copy found in the template directories. The built-in
+	// dialogs are used if none of them have it.
 	//
-	templatefile1 := filepath.Join(templates1, fmt.Sprintf("%s.json", dialog))
-	templatefile2 := filepath.Join(templates2, fmt.Sprintf("%s.json", dialog))
-	if fileExists(templatefile1) {
-		//
-		// The dialog is in the Resources directory of the application bundle
-		//
-		Str, _ := ioutil.ReadFile(templatefile1)
+	if Str, layer, err := findTemplate(layers, dialog); err == nil {
 		jsonStr = string(Str)
-	} else if fileExists(templatefile2) {
+		file = layer.path(layer.file(dialog))
+		trusted = templateTrusted(layer, dialog)
+	}
+	endpoint, payload, err := renderTemplate(dialog, file, jsonStr, dt.Slice()[1:])
+	if err != nil {
 		//
-		// The dialog is in the user's home directory area.
+		// Problems with the template are given back as json like the results.
 		//
-		Str, _ := ioutil.ReadFile(templatefile2)
-		jsonStr = string(Str)
+		errjson, _ := json.Marshal(map[string]string{"error": err.Error()})
+		fmt.Printf("%s", errjson)
+		return
 	}
-	if jsonStr[0] == '#' {
+
+	var result string
+	if endpoint == "dialog" && trusted {
 		//
-		// This is a dialog build using a json structure.
+		// Only the built-in templates and the user's own are trusted to run their
+		// scripts. The others are cleaned like any other caller's html.
 		//
-		re := regexp.MustCompile(`^#.*\r?\n`)
-		jsonStr = re.ReplaceAllString(jsonStr, "")
-		result := putRequest("http://localhost:9697/api/modal", strings.NewReader(jsonStr))
-		fmt.Printf("%s", result[1:len(result)-1])
+		result = sendRequest(http.MethodPut, "http://localhost:9697/api/dialog", strings.NewReader(payload), trustHeader, readTrustToken())
 	} else {
-		//
-		// This is a raw html template that needs the data combined to finish it.
-		//
-		re := regexp.MustCompile(`\r?\n`)
-		jsonStr = re.ReplaceAllString(jsonStr, " ")
-		renderC := RenderDialogContents(jsonStr, data)
-		result := putRequest("http://localhost:9697/api/dialog", strings.NewReader(renderC))
-		fmt.Printf("%s", result[1:len(result)-1])
+		result = putRequest(fmt.Sprintf("http://localhost:9697/api/%s", endpoint), strings.NewReader(payload))
 	}
+	fmt.Printf("%s", result)
 }
 
 //
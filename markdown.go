@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// The markdown converter. Raw html in the markdown is dropped by goldmark.
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// The policy for the html made from markdown. Like the policy for raw dialogs, images
+// are dropped so showing a message doesn't fetch anything from outside. Links are
+// kept since the message opens them in the browser, but only web and mail links.
+// Task list checkboxes and table alignment are kept too.
+var markdownPolicy = func() *bluemonday.Policy {
+	policy := bluemonday.NewPolicy()
+	policy.AllowElements("p", "br", "hr", "b", "i", "em", "strong", "del", "h1", "h2", "h3", "h4", "h5", "h6",
+		"ul", "ol", "li", "pre", "code", "blockquote", "table", "thead", "tbody", "tr", "th", "td")
+	policy.AllowAttrs("href").OnElements("a")
+	policy.AllowURLSchemes("http", "https", "mailto")
+	policy.RequireParseableURLs(true)
+	policy.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").OnElements("input")
+	policy.AllowStyles("text-align").OnElements("th", "td")
+	return policy
+}()
+
+// Function:     renderMarkdown
+//
+// Description:  This function changes markdown into sanitized html to show on the
+//
+//	BulletinBoard.
+//
+// Inputs:
+//
+//	text       The markdown to change
+func renderMarkdown(text string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(text), &buf); err != nil {
+		return "", err
+	}
+	return markdownPolicy.Sanitize(buf.String()), nil
+}
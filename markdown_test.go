@@ -0,0 +1,24 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Markdown keeps its formatting and web links but doesn't load anything from outside.
+func TestMarkdownDropsImages(t *testing.T) {
+	html, err := renderMarkdown("**Done** ~~old~~ [see](https://example.com) [run](javascript:alert(1)) ![logo](https://example.com/logo.png)\n\n- [x] built\n\n| a |\n|:-|\n| 1 |\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"<img", "src=", "logo", "javascript"} {
+		if strings.Contains(html, bad) {
+			t.Errorf("%q is in %q", bad, html)
+		}
+	}
+	for _, good := range []string{"<strong>Done</strong>", "<del>old</del>", `<a href="https://example.com">see</a>`, "run", `<input checked="" disabled="" type="checkbox"`, `<td style="text-align: left">1</td>`} {
+		if !strings.Contains(html, good) {
+			t.Errorf("%q is missing from %q", good, html)
+		}
+	}
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	rt "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// The levels a message can have. The frontend maps each to a theme color.
+var messageLevels = []string{"info", "success", "warning", "error"}
+
+// The formats a message can be given in.
+var messageFormats = []string{"text", "markdown"}
+
+const (
+	messageMinDisplay = 2 * time.Second // How long a message is shown before the next one
+	messageHistoryMax = 100             // How many messages the history keeps
+	messageMaxBytes   = 1 << 20         // The largest message body taken
+)
+
+// Struct:       Bulletin
+//
+// Description:  This is the message sent to the frontend to show.
+type Bulletin struct {
+	Message string `json:"msg"`
+	Title   string `json:"title"`
+	Level   string `json:"level"`
+	Format  string `json:"format,omitempty"`
+	Html    string `json:"html,omitempty"`
+}
+
+// Struct:       MessageRecord
+//
+// Description:  This is a message in the history with the time it was sent.
+type MessageRecord struct {
+	Time time.Time `json:"time"`
+	Bulletin
+}
+
+// Struct:       messageQueue
+//
+// Description:  This keeps the messages waiting to be shown and the history of the
+//
+//	messages sent. The worker shows and appends while holding the lock so an
+//	append can't pass the message it belongs to.
+type messageQueue struct {
+	lock       sync.Mutex
+	pending    []Msg
+	history    []MessageRecord
+	wake       chan struct{}
+	after      func(time.Duration) <-chan time.Time // The clock. Tests give their own.
+	appendText func(string)                         // Adds text to the message showing
+}
+
+// Function:     newMessageQueue
+//
+// Description:  This function makes an empty message queue.
+func newMessageQueue() *messageQueue {
+	return &messageQueue{wake: make(chan struct{}, 1), after: time.After}
+}
+
+// Function:     push
+//
+// Description:  This method adds a message to the end of the queue and to the history.
+//
+//	Empty messages, which hide the board, aren't kept in the history.
+//
+// Inputs:
+//
+//	msg        The message to add
+func (q *messageQueue) push(msg Msg) {
+	q.lock.Lock()
+	q.pending = append(q.pending, msg)
+	if strings.TrimSpace(msg.Message) != "" {
+		bulletin := msg.bulletin()
+		bulletin.Html = ""
+		q.history = append(q.history, MessageRecord{
+			Time:     time.Now(),
+			Bulletin: bulletin,
+		})
+		if len(q.history) > messageHistoryMax {
+			q.history = q.history[len(q.history)-messageHistoryMax:]
+		}
+	}
+	q.lock.Unlock()
+	q.wakeWorker()
+}
+
+// Function:     pushAppend
+//
+// Description:  This method adds text to the end of the message sent before it. When
+//
+//	that message is still waiting, the text is put on it. Otherwise it is the
+//	message showing, so the text is added to it right away. The text is added
+//	to that message in the history too.
+//
+// Inputs:
+//
+//	text       The text to add
+func (q *messageQueue) pushAppend(text string) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if len(q.history) > 0 {
+		q.history[len(q.history)-1].Message += text
+	}
+	if len(q.pending) > 0 {
+		q.pending[len(q.pending)-1].Message += text
+		return
+	}
+	if q.appendText != nil {
+		q.appendText(text)
+	}
+}
+
+// Function:     wakeWorker
+//
+// Description:  This method wakes the worker if it is waiting.
+func (q *messageQueue) wakeWorker() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Function:     showNext
+//
+// Description:  This method takes the next message off the queue and shows it.
+//
+// Inputs:
+//
+//	show       Shows a message
+func (q *messageQueue) showNext(show func(Msg)) (Msg, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if len(q.pending) == 0 {
+		return Msg{}, false
+	}
+	msg := q.pending[0]
+	q.pending = q.pending[1:]
+	show(msg)
+	return msg, true
+}
+
+// Function:     recent
+//
+// Description:  This method gives the messages in the history, oldest first. A limit
+//
+//	above zero only gives that many of the newest ones.
+//
+// Inputs:
+//
+//	limit      The number of messages to give
+func (q *messageQueue) recent(limit int) []MessageRecord {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	history := q.history
+	if limit > 0 && len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+	return append([]MessageRecord{}, history...)
+}
+
+// Function:     runMessages
+//
+// Description:  This method shows the queued messages on the BulletinBoard.
+//
+// Inputs:
+//
+//	ctx        The Wails runtime context
+func (a *App) runMessages(ctx context.Context) {
+	a.messages.run(ctx, func(msg Msg) {
+		a.showMessage(ctx, msg)
+	}, func(text string) {
+		rt.EventsEmit(ctx, "append", text)
+	})
+}
+
+// Function:     run
+//
+// Description:  This method shows the queued messages in order. Each message is left
+//
+//	up for at least messageMinDisplay before the next one replaces it. Appends
+//	to the message showing are added right away.
+//
+// Inputs:
+//
+//	ctx          Ends the worker when done
+//	show         Shows a message
+//	appendText   Adds text to the message showing
+func (q *messageQueue) run(ctx context.Context, show func(Msg), appendText func(string)) {
+	q.lock.Lock()
+	q.appendText = appendText
+	q.lock.Unlock()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		}
+		for {
+			msg, ok := q.showNext(show)
+			if !ok {
+				break
+			}
+			if strings.TrimSpace(msg.Message) == "" {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.after(messageMinDisplay):
+			}
+		}
+	}
+}
+
+// Function:     checkLevel
+//
+// Description:  This function makes sure a message level is one of the known levels.
+//
+//	An empty level is info.
+//
+// Inputs:
+//
+//	level      The level given
+func checkLevel(level string) (string, error) {
+	if level == "" {
+		return "info", nil
+	}
+	for _, known := range messageLevels {
+		if level == known {
+			return level, nil
+		}
+	}
+	return "", fmt.Errorf("the level %q isn't one of info, success, warning, or error", level)
+}
+
+// Function:     checkFormat
+//
+// Description:  This function makes sure a message format is one of the known formats.
+//
+//	An empty format is text.
+//
+// Inputs:
+//
+//	format     The format given
+func checkFormat(format string) (string, error) {
+	if format == "" {
+		return "text", nil
+	}
+	for _, known := range messageFormats {
+		if format == known {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("the format %q isn't text or markdown", format)
+}
+
+// Function:     bulletin
+//
+// Description:  This method makes the Bulletin to show for a message. A markdown
+//
+//	message is changed to sanitized html. If that fails, it is shown as text.
+func (msg Msg) bulletin() Bulletin {
+	bulletin := Bulletin{
+		Message: msg.Message,
+		Title:   msg.Title,
+		Level:   msg.Level,
+		Format:  msg.Format,
+	}
+	if msg.Format == "markdown" {
+		html, err := renderMarkdown(msg.Message)
+		if err != nil {
+			log.Printf("Unable to render the markdown message: %v", err)
+			bulletin.Format = "text"
+		} else {
+			bulletin.Html = html
+		}
+	}
+	return bulletin
+}
+
+// Function:     showMessage
+//
+// Description:  This method sends a message to the frontend. A message with a ttl is
+//
+//	taken down after that many seconds unless another message has been shown.
+//
+// Inputs:
+//
+//	ctx        The Wails runtime context
+//	msg        The message to show
+func (a *App) showMessage(ctx context.Context, msg Msg) {
+	seq := atomic.AddUint64(&a.messageSeq, 1)
+	rt.EventsEmit(ctx, "message", msg.bulletin())
+	if msg.TTL > 0 {
+		time.AfterFunc(time.Duration(msg.TTL)*time.Second, func() {
+			if atomic.LoadUint64(&a.messageSeq) == seq {
+				rt.EventsEmit(ctx, "message", Bulletin{Level: "info"})
+			}
+		})
+	}
+}
+
+// Function:     readMessage
+//
+// Description:  This function reads the message from the body of a request. A json
+//
+//	body is a Msg. A plain text body is the message itself with the title,
+//	level, and ttl taken from the query string. It gives the http status to
+//	answer with when the message can't be read.
+//
+// Inputs:
+//
+//	c          The gin context of the request
+func readMessage(c *gin.Context) (Msg, int, error) {
+	var msg Msg
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, messageMaxBytes)
+	switch c.ContentType() {
+	case "application/json":
+		if err := c.ShouldBindJSON(&msg); err != nil {
+			return msg, bodyErrorStatus(err), err
+		}
+
+	case "text/plain", "":
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return msg, bodyErrorStatus(err), err
+		}
+		if len(body) == 0 {
+			return msg, http.StatusBadRequest, fmt.Errorf("the message is empty")
+		}
+		msg.Message = string(body)
+		msg.Title = c.Query("title")
+		msg.Level = c.Query("level")
+		msg.Format = c.Query("format")
+		if ttl := c.Query("ttl"); ttl != "" {
+			if msg.TTL, err = strconv.Atoi(ttl); err != nil {
+				return msg, http.StatusBadRequest, fmt.Errorf("the ttl has to be a number of seconds")
+			}
+		}
+
+	default:
+		return msg, http.StatusUnsupportedMediaType, fmt.Errorf("a message has to be sent as application/json or text/plain, not %s", c.ContentType())
+	}
+	return checkMessage(msg)
+}
+
+// Function:     readUriMessage
+//
+// Description:  This function reads the message of the older routes that give it on
+//
+//	the URI string. A json body is optional. A message in it takes the place
+//	of the one on the URI.
+//
+// Inputs:
+//
+//	c          The gin context of the request
+func readUriMessage(c *gin.Context) (Msg, int, error) {
+	var msg Msg
+	if c.Request.ContentLength != 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, messageMaxBytes)
+		if err := json.NewDecoder(c.Request.Body).Decode(&msg); err != nil && !errors.Is(err, io.EOF) {
+			return msg, bodyErrorStatus(err), err
+		}
+	}
+	message := c.Param("message")
+	if msg.Message != "" {
+		message = msg.Message
+	}
+	message, err := url.QueryUnescape(message)
+	if err != nil {
+		return msg, http.StatusBadRequest, fmt.Errorf("the message isn't encoded right: %w", err)
+	}
+	msg.Message = message
+	return checkMessage(msg)
+}
+
+// Function:     checkMessage
+//
+// Description:  This function checks the level, format, and ttl of a message.
+//
+// Inputs:
+//
+//	msg        The message to check
+func checkMessage(msg Msg) (Msg, int, error) {
+	var err error
+	if msg.Level, err = checkLevel(msg.Level); err != nil {
+		return msg, http.StatusBadRequest, err
+	}
+	if msg.Format, err = checkFormat(msg.Format); err != nil {
+		return msg, http.StatusBadRequest, err
+	}
+	if msg.TTL < 0 {
+		return msg, http.StatusBadRequest, fmt.Errorf("the ttl can't be below zero")
+	}
+	return msg, http.StatusOK, nil
+}
+
+// Function:     bodyErrorStatus
+//
+// Description:  This function gives the http status for an error reading a body.
+//
+// Inputs:
+//
+//	err        The error given
+func bodyErrorStatus(err error) int {
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// The queue is run with a clock that only moves when the test says so.
+func TestAppendsLandOnTheirMessage(t *testing.T) {
+	q := newMessageQueue()
+	tick := make(chan time.Time)
+	q.after = func(time.Duration) <-chan time.Time { return tick }
+	events := make(chan string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.run(ctx, func(msg Msg) {
+		events <- "message " + msg.Message
+	}, func(text string) {
+		events <- "append " + text
+	})
+
+	next := func() string {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("nothing was shown")
+			return ""
+		}
+	}
+
+	q.push(Msg{Message: "A"})
+	if got := next(); got != "message A" {
+		t.Fatalf("got %q, want message A", got)
+	}
+
+	//
+	// A is showing and waiting out its time, so the append goes on it right away.
+	//
+	q.pushAppend("1")
+	if got := next(); got != "append 1" {
+		t.Fatalf("got %q, want append 1", got)
+	}
+
+	//
+	// B is waiting behind A, so its append is put on it and not on A.
+	//
+	q.push(Msg{Message: "B"})
+	q.pushAppend("2")
+	select {
+	case event := <-events:
+		t.Fatalf("%q was shown before A's time was up", event)
+	default:
+	}
+	tick <- time.Now()
+	if got := next(); got != "message B2" {
+		t.Fatalf("got %q, want message B2", got)
+	}
+
+	history := q.recent(0)
+	if len(history) != 2 || history[0].Message != "A1" || history[1].Message != "B2" {
+		t.Fatalf("the history is %+v", history)
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The formats a template can be in.
+var templateFormats = []string{"raw", "modal", "wizard"}
+
+// Struct:       TemplateParam
+//
+// Description:  This is a value a template expects from the command line. The values
+//
+//	given are matched to the parameters in order. Each is given to the template
+//	by its name and as dataN for the Nth one.
+type TemplateParam struct {
+	Name     string `yaml:"name" json:"name"`
+	Type     string `yaml:"type,omitempty" json:"type,omitempty"`
+	Default  string `yaml:"default,omitempty" json:"default,omitempty"`
+	Help     string `yaml:"help,omitempty" json:"help,omitempty"`
+	Required bool   `yaml:"required,omitempty" json:"required,omitempty"`
+}
+
+// Struct:       TemplateMeta
+//
+// Description:  This is the header at the top of a template between two --- lines.
+type TemplateMeta struct {
+	Name        string          `yaml:"name,omitempty" json:"name"`
+	Description string          `yaml:"description,omitempty" json:"description,omitempty"`
+	Author      string          `yaml:"author,omitempty" json:"author,omitempty"`
+	Version     string          `yaml:"version,omitempty" json:"version,omitempty"`
+	Format      string          `yaml:"format,omitempty" json:"format"`
+	Theme       string          `yaml:"theme,omitempty" json:"theme,omitempty"`
+	Assets      []string        `yaml:"assets,omitempty" json:"assets,omitempty"`
+	Parameters  []TemplateParam `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+}
+
+// Struct:       Template
+//
+// Description:  This is a template read from a file with its header taken off.
+type Template struct {
+	Meta     TemplateMeta
+	Body     string
+	BodyLine int    // The line of the file the body starts on
+	Kind     string // The language of the body: json, yaml, or toml
+	Header   bool   // True when the file has a --- header
+}
+
+// Function:     parseTemplate
+//
+// Description:  This function splits the header from the body of a template. Older
+//
+//	templates without a header are still read. A first line starting with #
+//	marks a modal dialog and the rest of the line is its description. That
+//	is only for json templates since # starts a comment in yaml and toml. A
+//	yaml template can start with the --- that begins a yaml document. It is
+//	only taken as a header when another --- ends it and it reads as a header.
+//	Without a format, a body with items is a modal dialog and one with pages
+//	is a wizard.
+//
+// Inputs:
+//
+//	name       The name of the template
+//	file       The name of the template file. Its extension gives the kind.
+//	content    The contents of the template file
+func parseTemplate(name string, file string, content string) (Template, error) {
+	content = strings.TrimPrefix(content, "\ufeff")
+	tmpl := Template{Body: content, BodyLine: 1, Kind: templateKind(file)}
+	if tmpl.Kind == "" {
+		return tmpl, fmt.Errorf("%s isn't a json, yaml, or toml template", file)
+	}
+	header, rest, found := splitHeader(content)
+	if found && tmpl.Kind == "yaml" {
+		//
+		// The fields of a header are known, so anything else means the --- lines
+		// are yaml document markers.
+		//
+		decoder := yaml.NewDecoder(strings.NewReader(header))
+		decoder.KnownFields(true)
+		var meta TemplateMeta
+		if err := decoder.Decode(&meta); err != nil && err != io.EOF {
+			found = false
+		}
+	}
+	switch {
+	case found:
+		if err := yaml.Unmarshal([]byte(header), &tmpl.Meta); err != nil {
+			return tmpl, fmt.Errorf("the header of %s can't be read: %w", name, err)
+		}
+		tmpl.Body = rest
+		tmpl.BodyLine = strings.Count(content[:len(content)-len(rest)], "\n") + 1
+		tmpl.Header = true
+
+	case hasHeader(content) && tmpl.Kind != "yaml":
+		return tmpl, fmt.Errorf("the header of %s doesn't end with a --- line", name)
+
+	case tmpl.Kind == "json" && strings.HasPrefix(content, "#"):
+		line, rest, _ := strings.Cut(content, "\n")
+		tmpl.Meta.Description = strings.TrimSpace(strings.TrimLeft(strings.TrimRight(line, "\r"), "#"))
+		tmpl.Meta.Format = "modal"
+		tmpl.Body = rest
+		tmpl.BodyLine = 2
+	}
+
+	if tmpl.Meta.Name == "" {
+		tmpl.Meta.Name = name
+	}
+	if tmpl.Meta.Format == "" && tmpl.Kind != "json" {
+		if values, err := tmpl.decode(); err == nil {
+			if _, ok := values["items"]; ok {
+				tmpl.Meta.Format = "modal"
+			}
+			if _, ok := values["pages"]; ok {
+				tmpl.Meta.Format = "wizard"
+			}
+		}
+	}
+	if tmpl.Meta.Format == "" {
+		tmpl.Meta.Format = "raw"
+	}
+	if tmpl.Meta.Format == "modal" {
+		if body, err := tmpl.bodyJson(); err == nil && isWizard(body) {
+			tmpl.Meta.Format = "wizard"
+		}
+	}
+	known := false
+	for _, format := range templateFormats {
+		known = known || tmpl.Meta.Format == format
+	}
+	if !known {
+		return tmpl, fmt.Errorf("the format %q of %s isn't raw, modal, or wizard", tmpl.Meta.Format, name)
+	}
+	for i, param := range tmpl.Meta.Parameters {
+		if param.Name == "" {
+			return tmpl, fmt.Errorf("parameter %d of %s doesn't have a name", i+1, name)
+		}
+		switch param.Type {
+		case "", "string", "number", "bool":
+		default:
+			return tmpl, fmt.Errorf("the parameter %s of %s has the type %q, but only string, number, and bool are known", param.Name, name, param.Type)
+		}
+	}
+	return tmpl, nil
+}
+
+// Function:     splitHeader
+//
+// Description:  This function splits a template that starts with a --- header into
+//
+//	the header and the rest. Found is false when there isn't a header or no
+//	--- line ends it.
+//
+// Inputs:
+//
+//	content    The contents of the template file
+func splitHeader(content string) (string, string, bool) {
+	if !hasHeader(content) {
+		return "", content, false
+	}
+	_, rest, _ := strings.Cut(strings.TrimPrefix(content, "\ufeff"), "\n")
+	var header strings.Builder
+	for rest != "" {
+		var line string
+		line, rest, _ = strings.Cut(rest, "\n")
+		if strings.TrimRight(line, "\r") == "---" {
+			return header.String(), rest, true
+		}
+		header.WriteString(line + "\n")
+	}
+	return "", content, false
+}
+
+// Function:     hasHeader
+//
+// Description:  This function tells if a template starts with a --- header.
+//
+// Inputs:
+//
+//	content    The contents of the template file
+func hasHeader(content string) bool {
+	content = strings.TrimPrefix(content, "\ufeff")
+	return strings.HasPrefix(content, "---\n") || strings.HasPrefix(content, "---\r\n")
+}
+
+// Function:     header
+//
+// Description:  This method gives the header to write at the top of a template.
+func (meta TemplateMeta) header() string {
+	var buf strings.Builder
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(meta); err != nil {
+		return ""
+	}
+	encoder.Close()
+	return "---\n" + buf.String() + "---\n"
+}
+
+// Function:     bindParameters
+//
+// Description:  This method checks the values given for a template against its
+//
+//	parameters and gives the data for rendering it. Templates without
+//	parameters take any values as data1, data2, and so on.
+//
+// Inputs:
+//
+//	args       The values given after the template name
+func (meta TemplateMeta) bindParameters(args []string) (map[string]string, error) {
+	data := make(map[string]string, len(args))
+	for i, arg := range args {
+		data[fmt.Sprintf("data%d", i+1)] = arg
+	}
+	if len(meta.Parameters) == 0 {
+		return data, nil
+	}
+	if len(args) > len(meta.Parameters) {
+		return nil, fmt.Errorf("%s takes %d values, but %d were given", meta.Name, len(meta.Parameters), len(args))
+	}
+	for i, param := range meta.Parameters {
+		val := param.Default
+		given := i < len(args)
+		if given {
+			val = args[i]
+		} else if param.Required {
+			return nil, fmt.Errorf("%s needs a value for %s", meta.Name, param.Name)
+		}
+		if given || val != "" {
+			switch param.Type {
+			case "number":
+				if _, err := strconv.ParseFloat(val, 64); err != nil {
+					return nil, fmt.Errorf("the value %q for %s isn't a number", val, param.Name)
+				}
+			case "bool":
+				if _, err := strconv.ParseBool(val); err != nil {
+					return nil, fmt.Errorf("the value %q for %s isn't true or false", val, param.Name)
+				}
+			}
+		}
+		data[param.Name] = val
+		data[fmt.Sprintf("data%d", i+1)] = val
+	}
+	return data, nil
+}
+
+// Function:     describeTemplate
+//
+// Description:  This function prints the header of a template for the user.
+//
+// Inputs:
+//
+//	name       The name of the template
+//	asJson     True to print it as json
+func describeTemplate(name string, asJson bool) error {
+	content, layer, err := findTemplate(templateLayers(), name)
+	if err != nil {
+		return err
+	}
+	file := layer.file(name)
+	tmpl, err := parseTemplate(name, file, string(content))
+	if err != nil {
+		return err
+	}
+	if asJson {
+		out, _ := json.MarshalIndent(struct {
+			TemplateMeta
+			Source string `json:"source"`
+		}{tmpl.Meta, layer.path(file)}, "", " ")
+		fmt.Println(string(out))
+		return nil
+	}
+	fmt.Printf("%s (%s)", tmpl.Meta.Name, tmpl.Meta.Format)
+	if tmpl.Meta.Version != "" {
+		fmt.Printf(" version %s", tmpl.Meta.Version)
+	}
+	if tmpl.Meta.Author != "" {
+		fmt.Printf(" by %s", tmpl.Meta.Author)
+	}
+	fmt.Println()
+	if tmpl.Meta.Description != "" {
+		fmt.Println(tmpl.Meta.Description)
+	}
+	fmt.Printf("Source: %s\n", layer.path(file))
+	if len(tmpl.Meta.Parameters) > 0 {
+		fmt.Println("\nParameters:")
+		for i, param := range tmpl.Meta.Parameters {
+			kind := param.Type
+			if kind == "" {
+				kind = "string"
+			}
+			line := fmt.Sprintf("  %d. %s (%s)", i+1, param.Name, kind)
+			if param.Required {
+				line += " required"
+			} else if param.Default != "" {
+				line += fmt.Sprintf(" default %q", param.Default)
+			}
+			if param.Help != "" {
+				line += " - " + param.Help
+			}
+			fmt.Println(line)
+		}
+	}
+	return nil
+}
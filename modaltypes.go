@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Struct:       ModalType
+//
+// Description:  This describes one of the item types a modal dialog can have. The
+//
+//	builder menu, the template validation and the frontend renderer all
+//	work from the same list so that they can not drift apart.
+type ModalType struct {
+	Name     string         // The name used for the modaltype field in a template.
+	Label    string         // The name shown to the user in the builder.
+	HtmlType string         // The html input type used to render it. Empty if it isn't an input.
+	Format   string         // A description of the format for the default value.
+	Pattern  *regexp.Regexp // The pattern a non-empty default value must match.
+}
+
+// The modal types supported by BulletinBoard. The order is the order used in the builder menu.
+var ModalTypes = []ModalType{
+	{Name: "label", Label: "Label"},
+	{Name: "input", Label: "Input", HtmlType: "text"},
+	{Name: "textarea", Label: "Textarea", HtmlType: "textarea"},
+	{Name: "number", Label: "Number", HtmlType: "number", Format: "a number", Pattern: regexp.MustCompile(`^-?[0-9]*\.?[0-9]+$`)},
+	{Name: "range", Label: "Range", HtmlType: "range", Format: "a number", Pattern: regexp.MustCompile(`^-?[0-9]*\.?[0-9]+$`)},
+	{Name: "selection", Label: "Selection", HtmlType: "select"},
+	{Name: "option", Label: "Selection Option"},
+	{Name: "radio", Label: "Radio", HtmlType: "radio"},
+	{Name: "checkbox", Label: "Checkbox", HtmlType: "checkbox", Format: "true or false", Pattern: regexp.MustCompile(`^(true|false)$`)},
+	{Name: "color", Label: "Color", HtmlType: "color", Format: "#rrggbb", Pattern: regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)},
+	{Name: "date", Label: "Date", HtmlType: "date", Format: "YYYY-MM-DD", Pattern: regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}$`)},
+	{Name: "datetime", Label: "Datetime", HtmlType: "datetime-local", Format: "YYYY-MM-DDThh:mm", Pattern: regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}(:[0-9]{2})?$`)},
+	{Name: "email", Label: "Email", HtmlType: "email", Format: "name@domain", Pattern: regexp.MustCompile(`^[^@\s]+@[^@\s]+$`)},
+	{Name: "file", Label: "File", HtmlType: "file", Format: "a file path"},
+	{Name: "month", Label: "Month", HtmlType: "month", Format: "YYYY-MM", Pattern: regexp.MustCompile(`^[0-9]{4}-[0-9]{2}$`)},
+	{Name: "password", Label: "Password", HtmlType: "password"},
+	{Name: "tel", Label: "Telephone", HtmlType: "tel"},
+	{Name: "time", Label: "Time", HtmlType: "time", Format: "hh:mm", Pattern: regexp.MustCompile(`^[0-9]{2}:[0-9]{2}(:[0-9]{2})?$`)},
+	{Name: "url", Label: "Url", HtmlType: "url", Format: "scheme://host/path", Pattern: regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://\S+$`)},
+	{Name: "week", Label: "Week", HtmlType: "week", Format: "YYYY-Www", Pattern: regexp.MustCompile(`^[0-9]{4}-W[0-9]{2}$`)},
+}
+
+// Older names written by the builder that map to a type above.
+var modalTypeAliases = map[string]string{
+	"telephone": "tel",
+	"select":    "selection",
+}
+
+// Function:     lookupModalType
+//
+// Description:  This function returns the modal type for the given name. Aliases
+//
+//	are resolved and case is ignored.
+//
+// Inputs:
+//
+//	name      The modaltype name from a template
+func lookupModalType(name string) (ModalType, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := modalTypeAliases[name]; ok {
+		name = alias
+	}
+	for _, mt := range ModalTypes {
+		if mt.Name == name {
+			return mt, true
+		}
+	}
+	return ModalType{}, false
+}
+
+// Function:     validateDefault
+//
+// Description:  This method checks a default value against the format for the type.
+//
+// Inputs:
+//
+//	value      The default value to check
+func (mt ModalType) validateDefault(value string) error {
+	if value == "" || mt.Pattern == nil || mt.Pattern.MatchString(value) {
+		return nil
+	}
+	return fmt.Errorf("the %s value %q should be %s", mt.Label, value, mt.Format)
+}
+
+// Function:     normalizeModalDialog
+//
+// Description:  This function checks every item of a modal dialog against the
+//
+//	supported types. It rewrites aliases to the real type name and sets the
+//	html type for the frontend to use.
+//
+// Inputs:
+//
+//	dialog     The modal dialog to check and update
+func normalizeModalDialog(dialog *ModalDialog) error {
+	for i := range dialog.Items {
+		item := &dialog.Items[i]
+		mt, ok := lookupModalType(item.ModelType)
+		if !ok {
+			return fmt.Errorf("item %q has an unknown modaltype %q", item.Id, item.ModelType)
+		}
+		if err := mt.validateDefault(item.Value); err != nil {
+			return fmt.Errorf("item %q: %w", item.Id, err)
+		}
+		if err := item.checkRules(); err != nil {
+			return err
+		}
+		item.ModelType = mt.Name
+		item.HtmlType = mt.HtmlType
+	}
+	return dialog.checkShowIf()
+}
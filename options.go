@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Struct:       OptionsSource
+//
+// Description:  This tells where the options of a selection come from. Only one of
+//
+//	them is used. Each line of the output of the shell command, the file, or
+//	the body of the url becomes an option. Blank lines are skipped.
+type OptionsSource struct {
+	Command string `json:"command,omitempty"`
+	File    string `json:"file,omitempty"`
+	Url     string `json:"url,omitempty"`
+}
+
+// How long a command or url has to give the options.
+const optionsTimeout = 10 * time.Second
+
+// Function:     read
+//
+// Description:  This method gets the option lines from the source.
+func (src OptionsSource) read() ([]string, error) {
+	var reader io.Reader
+	switch {
+	case src.Command != "":
+		ctx, cancel := context.WithTimeout(context.Background(), optionsTimeout)
+		defer cancel()
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.CommandContext(ctx, "cmd", "/C", src.Command)
+		} else {
+			cmd = exec.CommandContext(ctx, "sh", "-c", src.Command)
+		}
+		cmd.Stderr = os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("the command %q failed: %w", src.Command, err)
+		}
+		reader = strings.NewReader(string(out))
+
+	case src.File != "":
+		file := src.File
+		if strings.HasPrefix(file, "~/") {
+			file = filepath.Join(homeDir(), file[2:])
+		}
+		fh, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer fh.Close()
+		reader = fh
+
+	case src.Url != "":
+		client := &http.Client{Timeout: optionsTimeout}
+		resp, err := client.Get(src.Url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("the url %q gave the status %s", src.Url, resp.Status)
+		}
+		reader = resp.Body
+
+	default:
+		return nil, fmt.Errorf("optionsFrom needs a command, file, or url")
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// Function:     resolveOptions
+//
+// Description:  This function replaces the optionsFrom of each selection with option
+//
+//	items for the selection. It is done by the cli before the dialog is sent.
+//
+// Inputs:
+//
+//	dialog      The modal dialog to update
+func resolveOptions(dialog *ModalDialog) error {
+	var items []DialogItem
+	for _, item := range dialog.Items {
+		if item.OptionsFrom == nil {
+			items = append(items, item)
+			continue
+		}
+		if mt, _ := lookupModalType(item.ModelType); mt.Name != "selection" {
+			return fmt.Errorf("item %q has optionsFrom, but only a selection can use it", item.Id)
+		}
+		lines, err := item.OptionsFrom.read()
+		if err != nil {
+			return fmt.Errorf("item %q: %w", item.Id, err)
+		}
+		item.OptionsFrom = nil
+		if item.Value == "" && len(lines) > 0 {
+			item.Value = lines[0]
+		}
+		items = append(items, item)
+		for i, line := range lines {
+			items = append(items, DialogItem{
+				ModelType: "option",
+				Name:      fmt.Sprintf("%s-option%d", item.Id, i+1),
+				Id:        fmt.Sprintf("%s-option%d", item.Id, i+1),
+				Value:     line,
+				For:       item.Id,
+			})
+		}
+	}
+	dialog.Items = items
+	return nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aymerick/raymond"
+)
+
+// The partials that come with BulletinBoard. A partial of the same name in a partials
+// directory is used in place of one of these.
+//
+//go:embed partials/*.hbs
+var builtinPartials embed.FS
+
+// The file extensions a partial can have.
+var partialExtensions = []string{".hbs", ".html"}
+
+// The colors of the default theme. A theme file can change any of them.
+var defaultThemeColors = map[string]string{
+	"textAreaColor":   "#454158",
+	"backgroundColor": "#22212C",
+	"textColor":       "#80ffea",
+	"borderColor":     "#1B1A23",
+	"Cyan":            "#80FFEA",
+	"Green":           "#8AFF80",
+	"Orange":          "#FFCA80",
+	"Pink":            "#FF80BF",
+	"Purple":          "#9580FF",
+	"Red":             "#FF9580",
+	"Yellow":          "#FFFF80",
+}
+
+// Function:     jsonEscape
+//
+// Description:  This function escapes text to go inside of a json string. The quotes
+//
+//	around it are left off.
+//
+// Inputs:
+//
+//	text       The text to escape
+func jsonEscape(text string) string {
+	var buf strings.Builder
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(text)
+	escaped := strings.TrimSuffix(buf.String(), "\n")
+	return escaped[1 : len(escaped)-1]
+}
+
+// Function:     loadPartials
+//
+// Description:  This function reads the partials for the raw templates. They come from
+//
+//	the partials directories, like ~/.config/bulletinboard/partials, and the
+//	ones built in. The first one found of each name is used.
+func loadPartials() map[string]string {
+	partials := make(map[string]string)
+	add := func(files fs.FS) {
+		entries, err := fs.ReadDir(files, ".")
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			ext := filepath.Ext(entry.Name())
+			name := strings.TrimSuffix(entry.Name(), ext)
+			known := false
+			for _, partialExt := range partialExtensions {
+				known = known || ext == partialExt
+			}
+			if entry.IsDir() || !known || partials[name] != "" {
+				continue
+			}
+			if data, err := fs.ReadFile(files, entry.Name()); err == nil {
+				partials[name] = string(data)
+			}
+		}
+	}
+	for _, dir := range resourceDirs("partials") {
+		add(os.DirFS(dir))
+	}
+	builtin, _ := fs.Sub(builtinPartials, "partials")
+	add(builtin)
+	return partials
+}
+
+// Function:     jsonPartials
+//
+// Description:  This function gives the partials for a json template. The partials go
+//
+//	inside of a json string, so each is rendered with the data first and what
+//	it gives is escaped for the string. Its mustaches are escaped too so it
+//	is used just as it is.
+//
+// Inputs:
+//
+//	data       The data the template is rendered with
+//	theme      The theme for the color helper
+func jsonPartials(data map[string]string, theme string) map[string]string {
+	raw := loadPartials()
+	partials := make(map[string]string, len(raw))
+	for name, source := range raw {
+		rendered, err := renderContents(source, data, theme, raw)
+		if err != nil {
+			//
+			// It is left as it is so the problem is given where it is used.
+			//
+			partials[name] = source
+			continue
+		}
+		partials[name] = strings.ReplaceAll(jsonEscape(rendered), "{{", "\\{{")
+	}
+	return partials
+}
+
+// Function:     loadThemeColors
+//
+// Description:  This function gives the colors of a theme. The colors of the default
+//
+//	theme are used for any the theme doesn't have.
+//
+// Inputs:
+//
+//	theme      The name of the theme. Empty gives the default theme.
+func loadThemeColors(theme string) map[string]string {
+	colors := make(map[string]string, len(defaultThemeColors))
+	for name, color := range defaultThemeColors {
+		colors[name] = color
+	}
+	if theme == "" {
+		return colors
+	}
+	themefile := findResource("themes", fmt.Sprintf("%s.json", theme))
+	if themefile == "" {
+		return colors
+	}
+	data, err := os.ReadFile(themefile)
+	if err != nil {
+		return colors
+	}
+	var fields map[string]interface{}
+	if json.Unmarshal(data, &fields) == nil {
+		for name, val := range fields {
+			if color, ok := val.(string); ok {
+				colors[name] = color
+			}
+		}
+	}
+	return colors
+}
+
+// Function:     templateHelpers
+//
+// Description:  This function gives the helpers the raw templates can use.
+//
+//	default    {{default data2 "none"}} gives the value or the fallback if it is empty
+//	upper      {{upper data1}} gives the value in upper case
+//	lower      {{lower data1}} gives the value in lower case
+//	json       {{json data1}} escapes the value to go inside a json string
+//	date       {{date "2006-01-02"}} gives the time now, or the value="..." given,
+//	           in the Go time layout
+//	env        {{env "USER"}} gives an environment variable
+//	color      {{color "Red"}} gives a color of the template's theme, or of the
+//	           theme="..." given
+//
+// Inputs:
+//
+//	theme      The theme of the template
+func templateHelpers(theme string) map[string]interface{} {
+	return map[string]interface{}{
+		"default": func(value interface{}, fallback interface{}) interface{} {
+			if raymond.IsTrue(value) {
+				return value
+			}
+			return fallback
+		},
+		"upper": func(value interface{}) string {
+			return strings.ToUpper(raymond.Str(value))
+		},
+		"lower": func(value interface{}) string {
+			return strings.ToLower(raymond.Str(value))
+		},
+		"json": func(value interface{}) raymond.SafeString {
+			return raymond.SafeString(jsonEscape(raymond.Str(value)))
+		},
+		"date": func(layout string, options *raymond.Options) string {
+			when := time.Now()
+			if value := options.HashStr("value"); value != "" {
+				parsed, err := time.Parse(time.RFC3339, value)
+				if err != nil {
+					parsed, err = time.Parse("2006-01-02", value)
+				}
+				if err != nil {
+					return value
+				}
+				when = parsed
+			}
+			return when.Format(layout)
+		},
+		"env": func(name string) string {
+			return os.Getenv(name)
+		},
+		"color": func(name string, options *raymond.Options) string {
+			colors := loadThemeColors(theme)
+			if other := options.HashStr("theme"); other != "" {
+				colors = loadThemeColors(other)
+			}
+			return colors[name]
+		},
+	}
+}
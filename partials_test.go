@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A partial that calls a helper with a string has to work in a json template.
+func TestJsonPartialWithHelper(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	dir := filepath.Join(config, resourceName, "partials")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	partial := "<b style=\"color: {{color \"Red\"}}\">{{data1}}</b>\n"
+	if err := os.WriteFile(filepath.Join(dir, "redtext.hbs"), []byte(partial), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	content := `{ "html": "{{> redtext}} {{> bbstyle}}", "width": 200, "height": 80, "x": 1, "y": 1 }`
+	endpoint, payload, err := renderTemplate("red", "red.json", content, []string{"hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoint != "dialog" {
+		t.Fatalf("the endpoint is %s", endpoint)
+	}
+	var dialog Dialog
+	if err := json.Unmarshal([]byte(payload), &dialog); err != nil {
+		t.Fatal(err)
+	}
+	want := "<b style=\"color: " + defaultThemeColors["Red"] + "\">hi</b>\n"
+	if !strings.HasPrefix(dialog.Html, want) {
+		t.Fatalf("the html is %q, want it to start with %q", dialog.Html, want)
+	}
+	if !strings.Contains(dialog.Html, "<style>") {
+		t.Fatalf("the bbstyle partial is missing from %q", dialog.Html)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	rt "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Struct:       Progress
+//
+// Description:  This is a progress bar for a long running script. A total of zero
+//
+//	shows just the count since there isn't an end to measure against.
+type Progress struct {
+	Id      string `json:"id"`
+	Title   string `json:"title"`
+	Total   int    `json:"total"`
+	Current int    `json:"current"`
+	Status  string `json:"status"`
+}
+
+// How often pipeProgress sends the count to the BulletinBoard.
+const progressPipeInterval = 100 * time.Millisecond
+
+// Struct:       ProgressStart
+//
+// Description:  This is the body for starting a progress bar.
+type ProgressStart struct {
+	Title string `json:"title"`
+	Total int    `json:"total"`
+}
+
+// Struct:       ProgressUpdate
+//
+// Description:  This is the body for updating a progress bar. Only the fields given
+//
+//	are changed.
+type ProgressUpdate struct {
+	Current *int    `json:"current"`
+	Status  *string `json:"status"`
+}
+
+// Struct:       progressBars
+//
+// Description:  This keeps the progress bars that haven't been finished.
+type progressBars struct {
+	lock sync.Mutex
+	bars map[string]*Progress
+}
+
+// Function:     newProgressBars
+//
+// Description:  This function makes an empty set of progress bars.
+func newProgressBars() *progressBars {
+	return &progressBars{bars: make(map[string]*Progress)}
+}
+
+// Function:     start
+//
+// Description:  This method starts a new progress bar and shows it.
+//
+// Inputs:
+//
+//	ctx        The Wails runtime context
+//	req        The title and total of the bar
+func (p *progressBars) start(ctx context.Context, req ProgressStart) (Progress, error) {
+	if req.Total < 0 {
+		return Progress{}, fmt.Errorf("the total can't be below zero")
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	bar := &Progress{
+		Id:    newRequestId(),
+		Title: req.Title,
+		Total: req.Total,
+	}
+	p.bars[bar.Id] = bar
+	rt.EventsEmit(ctx, "progress", *bar)
+	return *bar, nil
+}
+
+// Function:     update
+//
+// Description:  This method changes the count or status of a progress bar and shows it.
+//
+// Inputs:
+//
+//	ctx        The Wails runtime context
+//	id         The id of the bar
+//	req        The fields to change
+func (p *progressBars) update(ctx context.Context, id string, req ProgressUpdate) (Progress, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	bar, ok := p.bars[id]
+	if !ok {
+		return Progress{}, false
+	}
+	if req.Current != nil {
+		bar.Current = *req.Current
+		if bar.Total > 0 && bar.Current > bar.Total {
+			bar.Current = bar.Total
+		}
+	}
+	if req.Status != nil {
+		bar.Status = *req.Status
+	}
+	rt.EventsEmit(ctx, "progress", *bar)
+	return *bar, true
+}
+
+// Function:     done
+//
+// Description:  This method finishes a progress bar and takes it down.
+//
+// Inputs:
+//
+//	ctx        The Wails runtime context
+//	id         The id of the bar
+func (p *progressBars) done(ctx context.Context, id string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.bars[id]; !ok {
+		return false
+	}
+	delete(p.bars, id)
+	rt.EventsEmit(ctx, "progressdone", id)
+	return true
+}
+
+// Function:     progressRequest
+//
+// Description:  This function sends a request for a progress bar to the BulletinBoard
+//
+//	and reads the bar given back.
+//
+// Inputs:
+//
+//	method     The http method to use
+//	uri        The url to send the request
+//	body       The value to send as json, or nil
+func progressRequest(method string, uri string, body interface{}) (Progress, error) {
+	var data io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return Progress{}, err
+		}
+		data = bytes.NewReader(buf)
+	}
+	result, err := trySendRequest(method, uri, data)
+	if err != nil {
+		return Progress{}, err
+	}
+	var reply struct {
+		Progress
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(result), &reply); err != nil {
+		return Progress{}, fmt.Errorf("the BulletinBoard gave back %q", result)
+	}
+	if reply.Error != "" {
+		return Progress{}, errors.New(reply.Error)
+	}
+	return reply.Progress, nil
+}
+
+// Function:     startProgress
+//
+// Description:  This function starts a progress bar on the BulletinBoard.
+//
+// Inputs:
+//
+//	title      The title to show above the bar
+//	total      The count the work is done at
+func startProgress(title string, total int) (Progress, error) {
+	return progressRequest(http.MethodPost, "http://localhost:9697/api/progress", ProgressStart{Title: title, Total: total})
+}
+
+// Function:     updateProgress
+//
+// Description:  This function updates a progress bar on the BulletinBoard.
+//
+// Inputs:
+//
+//	id         The id of the bar
+//	update     The fields to change
+func updateProgress(id string, update ProgressUpdate) error {
+	_, err := progressRequest(http.MethodPatch, "http://localhost:9697/api/progress/"+url.PathEscape(id), update)
+	return err
+}
+
+// Function:     doneProgress
+//
+// Description:  This function finishes a progress bar on the BulletinBoard.
+//
+// Inputs:
+//
+//	id         The id of the bar
+func doneProgress(id string) error {
+	_, err := progressRequest(http.MethodDelete, "http://localhost:9697/api/progress/"+url.PathEscape(id), nil)
+	return err
+}
+
+// Function:     pipeProgress
+//
+// Description:  This function copies the lines of the input to the output and counts
+//
+//	them on a progress bar. The last line is shown as the status. Updates are
+//	sent at most every progressPipeInterval so fast output doesn't flood the
+//	BulletinBoard. The input is always copied, even when the bar can't be
+//	shown. Problems with the bar are only warned about on stderr.
+//
+// Inputs:
+//
+//	title      The title to show above the bar
+//	total      The number of lines expected
+//	in         Where the lines come from
+//	out        Where the lines are copied to
+func pipeProgress(title string, total int, in io.Reader, out io.Writer) error {
+	bar, err := startProgress(title, total)
+	started := err == nil
+	showing := started
+	if !started {
+		fmt.Fprintf(os.Stderr, "bb: the progress bar can't be shown: %v\n", err)
+	}
+	update := func(count int, line string) {
+		if !showing {
+			return
+		}
+		if err := updateProgress(bar.Id, ProgressUpdate{Current: &count, Status: &line}); err != nil {
+			fmt.Fprintf(os.Stderr, "bb: the progress bar can't be updated: %v\n", err)
+			showing = false
+		}
+	}
+
+	//
+	// The lines are copied exactly as they come, so a long line or a last line
+	// without a newline isn't changed.
+	//
+	count := 0
+	var line string
+	var sent time.Time
+	reader := bufio.NewReader(in)
+	var readErr error
+	for readErr == nil {
+		var chunk string
+		chunk, readErr = reader.ReadString('\n')
+		if chunk == "" {
+			continue
+		}
+		if _, err := io.WriteString(out, chunk); err != nil {
+			readErr = err
+			break
+		}
+		line = strings.TrimRight(chunk, "\r\n")
+		count++
+		if time.Since(sent) >= progressPipeInterval {
+			update(count, line)
+			sent = time.Now()
+		}
+	}
+	update(count, line)
+	if started {
+		if err := doneProgress(bar.Id); err != nil {
+			fmt.Fprintf(os.Stderr, "bb: the progress bar can't be finished: %v\n", err)
+		}
+	}
+	if readErr == io.EOF {
+		return nil
+	}
+	return readErr
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aymerick/raymond"
+)
+
+// Finds the line numbers in the errors from raymond.
+var renderLineRe = regexp.MustCompile(`line (\d+)`)
+
+// Function:     renderTemplate
+//
+// Description:  This function makes the json that bb send template puts to the
+//
+//	BulletinBoard and the endpoint it goes to: dialog, modal, or wizard. Errors
+//	give the line of the template file or the column of the rendered json.
+//
+// Inputs:
+//
+//	name       The name of the template
+//	file       The path of the template file
+//	content    The contents of the template file
+//	args       The values given after the template name
+func renderTemplate(name string, file string, content string, args []string) (string, string, error) {
+	tmpl, err := parseTemplate(name, file, content)
+	if err != nil {
+		return "", "", err
+	}
+
+	//
+	// Check the rest of the command line against the parameters of the template
+	// and make the data needed for the dialog template.
+	//
+	data, err := tmpl.Meta.bindParameters(args)
+	if err != nil {
+		return "", "", err
+	}
+
+	if tmpl.Meta.Format != "raw" {
+		//
+		// This is a dialog build using a json structure.
+		//
+		body, err := tmpl.bodyJson()
+		if err != nil {
+			return "", "", fmt.Errorf("%s: %w", name, err)
+		}
+		payload, err := prepareModalTemplate(body, name)
+		if err != nil {
+			if tmpl.Kind == "json" {
+				err = jsonErrorPosition(err, tmpl.Body, tmpl.BodyLine)
+			}
+			return "", "", fmt.Errorf("%s: %w", name, err)
+		}
+		return tmpl.Meta.Format, payload, nil
+	}
+
+	if tmpl.Kind != "json" {
+		//
+		// A yaml or toml template is read before it is rendered, so the html keeps
+		// its newlines.
+		//
+		dialog, err := tmpl.rawDialog(file, data)
+		if err != nil {
+			return "", "", fmt.Errorf("%s: %w", name, err)
+		}
+		if dialog.Name == "" {
+			dialog.Name = name
+		}
+		payload, err := json.Marshal(dialog)
+		return "dialog", string(payload), err
+	}
+
+	//
+	// This is a raw html template that needs the data combined to finish it. It is
+	// parsed with its newlines first so a problem is given on the right line.
+	//
+	if _, err := raymond.Parse(tmpl.Body); err != nil {
+		return "", "", fmt.Errorf("%s: %s", name, renderLineRe.ReplaceAllStringFunc(err.Error(), func(match string) string {
+			line, _ := strconv.Atoi(strings.TrimPrefix(match, "line "))
+			return fmt.Sprintf("line %d", line+tmpl.BodyLine-1)
+		}))
+	}
+	re := regexp.MustCompile(`\r?\n`)
+	rendered, err := RenderDialogContents(re.ReplaceAllString(tmpl.Body, " "), data, tmpl.Meta.Theme)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", name, err)
+	}
+	var dialog Dialog
+	if err := json.Unmarshal([]byte(rendered), &dialog); err != nil {
+		return "", "", fmt.Errorf("%s doesn't render to a dialog: %w", name, jsonErrorPosition(err, rendered, 0))
+	}
+	return "dialog", nameDialog(rendered, name), nil
+}
+
+// Function:     jsonErrorPosition
+//
+// Description:  This function adds where a json syntax error is to the error. A start
+//
+//	line above zero gives the line and column in the file. Otherwise the
+//	column of the rendered json and the text around it are given.
+//
+// Inputs:
+//
+//	err        The error from reading the json
+//	text       The json read
+//	startLine  The line of the file the json starts on, or 0
+func jsonErrorPosition(err error, text string, startLine int) error {
+	var syntax *json.SyntaxError
+	if !errors.As(err, &syntax) {
+		return err
+	}
+	offset := int(syntax.Offset)
+	if offset > len(text) {
+		offset = len(text)
+	}
+	if startLine > 0 {
+		before := text[:offset]
+		line := startLine + strings.Count(before, "\n")
+		column := offset - strings.LastIndex(before, "\n")
+		return fmt.Errorf("%w at line %d, column %d", err, line, column)
+	}
+	from := offset - 30
+	if from < 0 {
+		from = 0
+	}
+	return fmt.Errorf("%w at column %d near %q", err, offset, text[from:offset])
+}
+
+// Function:     printRender
+//
+// Description:  This function prints what bb send template would send for a template
+//
+//	without sending it. Selections with optionsFrom still get their options.
+//
+// Inputs:
+//
+//	name         The name of the template
+//	args         The values given after the template name
+//	payloadOnly  True to print only the json that would be sent
+func printRender(name string, args []string, payloadOnly bool) error {
+	content, layer, err := findTemplate(templateLayers(), name)
+	if err != nil {
+		return err
+	}
+	endpoint, payload, err := renderTemplate(name, layer.path(layer.file(name)), string(content), args)
+	if err != nil {
+		return err
+	}
+	var pretty interface{}
+	if err := json.Unmarshal([]byte(payload), &pretty); err != nil {
+		return err
+	}
+	out := pretty
+	if !payloadOnly {
+		out = map[string]interface{}{
+			"method":   "PUT",
+			"endpoint": "/api/" + endpoint,
+			"payload":  pretty,
+		}
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", " ")
+	return encoder.Encode(out)
+}
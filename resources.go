@@ -0,0 +1,169 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// The name of the directory BulletinBoard keeps its files in under each base directory.
+const resourceName = "bulletinboard"
+
+// Function:     homeDir
+//
+// Description:  This function gives the home directory of the user.
+func homeDir() string {
+	if home, err := os.UserHomeDir(); err == nil {
+		return home
+	}
+	return os.Getenv("HOME")
+}
+
+// Function:     configDir
+//
+// Description:  This function gives the directory for the user's files. It is under
+//
+//	XDG_CONFIG_HOME if that is set and ~/.config otherwise, on every OS.
+func configDir() string {
+	if config := os.Getenv("XDG_CONFIG_HOME"); config != "" && filepath.IsAbs(config) {
+		return filepath.Join(config, resourceName)
+	}
+	return filepath.Join(homeDir(), ".config", resourceName)
+}
+
+// Function:     configPath
+//
+// Description:  This function gives the path of a file or directory in the user's
+//
+//	directory.
+//
+// Inputs:
+//
+//	name       The name of the file or directory
+func configPath(name string) string {
+	return filepath.Join(configDir(), name)
+}
+
+// Function:     userDir
+//
+// Description:  This function gives the user's directory for a kind of resource, like
+//
+//	dialogs or themes. It is where new ones are made.
+//
+// Inputs:
+//
+//	kind       The kind of resource
+func userDir(kind string) string {
+	return configPath(kind)
+}
+
+// Function:     dataDirs
+//
+// Description:  This function gives the system data directories from XDG_DATA_DIRS.
+//
+//	They default to /usr/local/share and /usr/share.
+func dataDirs() []string {
+	dirs := os.Getenv("XDG_DATA_DIRS")
+	if dirs == "" {
+		dirs = "/usr/local/share:/usr/share"
+	}
+	var list []string
+	for _, dir := range filepath.SplitList(dirs) {
+		if filepath.IsAbs(dir) {
+			list = append(list, filepath.Join(dir, resourceName))
+		}
+	}
+	return list
+}
+
+// Function:     installDirs
+//
+// Description:  This function gives the directories installed with the program. On
+//
+//	macOS that is the Resources directory of the app bundle. Elsewhere, the
+//	resources can sit next to the program.
+func installDirs() []string {
+	prog, err := os.Executable()
+	if err != nil {
+		return nil
+	}
+	if resolved, err := filepath.EvalSymlinks(prog); err == nil {
+		prog = resolved
+	}
+	progDir := filepath.Dir(prog)
+	if runtime.GOOS == "darwin" {
+		return []string{filepath.Join(progDir, "../Resources")}
+	}
+	return []string{progDir}
+}
+
+// Function:     resourceDirs
+//
+// Description:  This function gives the directories to look for a kind of resource in,
+//
+//	in the order they are searched. The user's come first so they can take
+//	the place of any other, then the directories in BB_TEMPLATE_PATH for
+//	dialogs, then the ones installed with the program, then the system data
+//	directories. Directories that don't exist are left out except the user's.
+//
+// Inputs:
+//
+//	kind       The kind of resource, like dialogs or themes
+func resourceDirs(kind string) []string {
+	dirs := []string{userDir(kind)}
+	if kind == "dialogs" {
+		for _, dir := range filepath.SplitList(os.Getenv("BB_TEMPLATE_PATH")) {
+			if strings.HasPrefix(dir, "~/") {
+				dir = filepath.Join(homeDir(), dir[2:])
+			}
+			if dir != "" {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	for _, dir := range installDirs() {
+		dirs = append(dirs, filepath.Join(dir, kind))
+	}
+	for _, dir := range dataDirs() {
+		dirs = append(dirs, filepath.Join(dir, kind))
+	}
+
+	//
+	// Drop the repeats and the ones that aren't there.
+	//
+	var found []string
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		dir = filepath.Clean(dir)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		if isDir, _ := isDirectory(dir); isDir || dir == userDir(kind) {
+			found = append(found, dir)
+		}
+	}
+	return found
+}
+
+// Function:     findResource
+//
+// Description:  This function finds the first file with the given name in the
+//
+//	directories for a kind of resource. It gives an empty string if there
+//	isn't one.
+//
+// Inputs:
+//
+//	kind       The kind of resource, like dialogs or themes
+//	file       The name of the file
+func findResource(kind string, file string) string {
+	for _, dir := range resourceDirs(kind) {
+		path := filepath.Join(dir, file)
+		if fileExists(path) {
+			return path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	rt "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// The status values a dialog can end with.
+const (
+	StatusSubmitted = "submitted"
+	StatusCanceled  = "canceled"
+	StatusTimeout   = "timeout"
+)
+
+// Struct:       DialogResult
+//
+// Description:  This is what the dialog endpoints and the cli give back. The button
+//
+//	is the id of the button pressed and the action is its action. The values
+//	are keyed by the item name. Checkboxes are booleans and numbers are numbers.
+type DialogResult struct {
+	Status string                 `json:"status"`
+	Button string                 `json:"button,omitempty"`
+	Action string                 `json:"action,omitempty"`
+	Values map[string]interface{} `json:"values"`
+}
+
+// Counts the dialogs shown. The lock keeps a dialog being taken down from racing
+// with a new one being shown.
+var (
+	dialogLock sync.Mutex
+	dialogSeq  uint64
+)
+
+// Function:     listenForReturn
+//
+// Description:  This function sets up a listener for the next dialog return from the
+//
+//	frontend. It has to be called before the dialog is sent so no return is missed.
+//
+// Inputs:
+//
+//	ctx        The Wails runtime context
+func listenForReturn(ctx context.Context) chan []interface{} {
+	returned := make(chan []interface{}, 1)
+	rt.EventsOnce(ctx, "dialogreturn", func(optionalData ...interface{}) {
+		returned <- optionalData
+	})
+	return returned
+}
+
+// Function:     showDialog
+//
+// Description:  This function listens for the return of a dialog and then sends the
+//
+//	dialog to the frontend. It gives the number of the dialog so a dialog that
+//	has been replaced by a newer one doesn't take the newer one down.
+//
+// Inputs:
+//
+//	ctx        The Wails runtime context
+//	event      The event to send the dialog with
+//	dialog     The dialog to send
+func showDialog(ctx context.Context, event string, dialog interface{}) (chan []interface{}, uint64) {
+	dialogLock.Lock()
+	defer dialogLock.Unlock()
+	dialogSeq++
+	returned := listenForReturn(ctx)
+	rt.EventsEmit(ctx, event, dialog)
+	return returned, dialogSeq
+}
+
+// Function:     closeDialog
+//
+// Description:  This function stops listening for the return of a dialog and takes it
+//
+//	down if it is still the one shown.
+//
+// Inputs:
+//
+//	ctx        The Wails runtime context
+//	seq        The number of the dialog from showDialog
+func closeDialog(ctx context.Context, seq uint64) {
+	dialogLock.Lock()
+	defer dialogLock.Unlock()
+	if seq != dialogSeq {
+		return
+	}
+	rt.EventsOff(ctx, "dialogreturn")
+	rt.EventsEmit(ctx, "dialogclose")
+}
+
+// Function:     waitForReturn
+//
+// Description:  This function waits for the frontend to return the dialog. When the
+//
+//	timeout in seconds is reached first, the dialog is closed and the timeout
+//	status is given. When the caller goes away first, like bb dev replacing
+//	its dialog, the dialog is closed and the canceled status is given. A
+//	timeout of zero waits for as long as it takes.
+//
+// Inputs:
+//
+//	ctx        The Wails runtime context
+//	done       Closed when the caller has gone away
+//	returned   The channel from showDialog
+//	seq        The number of the dialog from showDialog
+//	timeout    The number of seconds to wait
+func waitForReturn(ctx context.Context, done <-chan struct{}, returned chan []interface{}, seq uint64, timeout int) ([]interface{}, string) {
+	var timer <-chan time.Time
+	if timeout > 0 {
+		timer = time.After(time.Duration(timeout) * time.Second)
+	}
+	select {
+	case optionalData := <-returned:
+		return optionalData, StatusSubmitted
+	case <-timer:
+		//
+		// Nobody answered. Stop listening and take the dialog down.
+		//
+		closeDialog(ctx, seq)
+		return nil, StatusTimeout
+	case <-done:
+		closeDialog(ctx, seq)
+		return nil, StatusCanceled
+	}
+}
+
+// Function:     parseModalReturn
+//
+// Description:  This function gets the id of the button pressed and the values from a
+//
+//	modal dialog return. The values are keyed by the item name and are as the
+//	frontend gave them. A button that doesn't submit values doesn't have any.
+//
+// Inputs:
+//
+//	optionalData   The data from the dialogreturn event
+func parseModalReturn(optionalData []interface{}) (string, map[string]interface{}) {
+	values := make(map[string]interface{})
+	if len(optionalData) == 0 {
+		return "", values
+	}
+	ret, ok := optionalData[0].(map[string]interface{})
+	if !ok {
+		return "", values
+	}
+	button, _ := ret["button"].(string)
+	list, _ := ret["values"].([]interface{})
+	for _, entry := range list {
+		if field, ok := entry.(map[string]interface{}); ok {
+			if name, ok := field["name"].(string); ok {
+				values[name] = field["value"]
+			}
+		}
+	}
+	return button, values
+}
+
+// Function:     typedValues
+//
+// Description:  This function converts the values from the frontend to the type of
+//
+//	the item they came from. Checkboxes become booleans and numbers become numbers.
+//
+// Inputs:
+//
+//	dialog     The modal dialog that was shown
+//	values     The values keyed by the item name
+func typedValues(dialog ModalDialog, values map[string]interface{}) map[string]interface{} {
+	typed := make(map[string]interface{}, len(values))
+	for key, val := range values {
+		typed[key] = val
+	}
+	for _, item := range dialog.Items {
+		val, ok := values[item.Name]
+		if !ok {
+			continue
+		}
+		switch item.ModelType {
+		case "checkbox":
+			switch v := val.(type) {
+			case bool:
+				typed[item.Name] = v
+			case string:
+				typed[item.Name] = v == "true"
+			default:
+				typed[item.Name] = false
+			}
+
+		case "number", "range":
+			switch v := val.(type) {
+			case float64:
+				typed[item.Name] = v
+			case string:
+				if num, err := strconv.ParseFloat(v, 64); err == nil {
+					typed[item.Name] = num
+				} else {
+					typed[item.Name] = nil
+				}
+			}
+		}
+	}
+	return typed
+}
+
+// Function:     rawResult
+//
+// Description:  This function makes the result for a raw html dialog. An object set as
+//
+//	the dialog result becomes the values. Anything else is given as the "value" value.
+//
+// Inputs:
+//
+//	optionalData   The data from the dialogreturn event
+func rawResult(optionalData []interface{}) DialogResult {
+	result := DialogResult{
+		Status: StatusSubmitted,
+		Values: make(map[string]interface{}),
+	}
+	if len(optionalData) == 0 {
+		return result
+	}
+	ret, ok := optionalData[0].(map[string]interface{})
+	if !ok {
+		return result
+	}
+	if status, _ := ret["status"].(string); status == StatusCanceled {
+		result.Status = StatusCanceled
+		return result
+	}
+	if button, ok := ret["button"].(string); ok {
+		result.Button = button
+	}
+	if values, ok := ret["value"].(map[string]interface{}); ok {
+		result.Values = values
+	} else if val, ok := ret["value"]; ok && val != nil {
+		result.Values["value"] = val
+	}
+	return result
+}
+
+// Function:     rawPasswordItems
+//
+// Description:  This function gives the values of a raw dialog that came from password
+//
+//	inputs as password items, so the audit log can hide them like the ones of
+//	a modal dialog. The frontend names them in the passwords of the return.
+//
+// Inputs:
+//
+//	optionalData   The data returned by the frontend
+func rawPasswordItems(optionalData []interface{}) []DialogItem {
+	if len(optionalData) == 0 {
+		return nil
+	}
+	ret, ok := optionalData[0].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	names, _ := ret["passwords"].([]interface{})
+	var items []DialogItem
+	for _, name := range names {
+		if name, ok := name.(string); ok {
+			items = append(items, DialogItem{ModelType: "password", Name: name})
+		}
+	}
+	return items
+}
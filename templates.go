@@ -0,0 +1,246 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The dialogs that come with BulletinBoard. They are used when no directory has a
+// template with the same name, so a plain binary has them too.
+//
+//go:embed dialogs/*.json
+var builtinDialogs embed.FS
+
+// The name given for the source of the built-in dialogs.
+const builtinSource = "builtin"
+
+// The file extensions a template can have in the order they are looked for.
+var templateExtensions = []string{".json", ".yaml", ".yml", ".toml"}
+
+// Struct:       templateLayer
+//
+// Description:  This is one place templates are found. Dir is the directory, or
+//
+//	builtinSource for the dialogs in the program.
+type templateLayer struct {
+	Dir   string
+	Files fs.FS
+}
+
+// Function:     templateLayers
+//
+// Description:  This function gives the places templates are found in the order they
+//
+//	are searched. The built-in dialogs are last.
+func templateLayers() []templateLayer {
+	var layers []templateLayer
+	for _, dir := range resourceDirs("dialogs") {
+		layers = append(layers, templateLayer{Dir: dir, Files: os.DirFS(dir)})
+	}
+	builtin, _ := fs.Sub(builtinDialogs, "dialogs")
+	return append(layers, templateLayer{Dir: builtinSource, Files: builtin})
+}
+
+// Function:     readOnly
+//
+// Description:  This method tells if the templates of the layer can't be changed.
+func (layer templateLayer) readOnly() bool {
+	return layer.Dir == builtinSource
+}
+
+// Function:     path
+//
+// Description:  This method gives the path of a template file in the layer for
+//
+//	showing to the user.
+//
+// Inputs:
+//
+//	file       The name of the file
+func (layer templateLayer) path(file string) string {
+	if layer.readOnly() {
+		return builtinSource + ":" + file
+	}
+	return filepath.Join(layer.Dir, file)
+}
+
+// Function:     file
+//
+// Description:  This method gives the name of the file of a template in the layer, or
+//
+//	an empty string if the layer doesn't have it.
+//
+// Inputs:
+//
+//	name       The name of the template
+func (layer templateLayer) file(name string) string {
+	for _, ext := range templateExtensions {
+		if info, err := fs.Stat(layer.Files, name+ext); err == nil && !info.IsDir() {
+			return name + ext
+		}
+	}
+	return ""
+}
+
+// Function:     names
+//
+// Description:  This method gives the names of the templates in the layer. Directories
+//
+//	and files that aren't templates are left out.
+func (layer templateLayer) names() []string {
+	entries, err := fs.ReadDir(layer.Files, ".")
+	if err != nil {
+		return nil
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() || !entry.Type().IsRegular() && entry.Type()&fs.ModeSymlink == 0 {
+			continue
+		}
+		if templateKind(entry.Name()) == "" || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		name := FilenameWithoutExtension(entry.Name())
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Struct:       TemplateInfo
+//
+// Description:  This tells about one copy of a template for bb list. A copy is shadowed
+//
+//	when a copy of the same name is found before it.
+type TemplateInfo struct {
+	Name        string `json:"name"`
+	Source      string `json:"source"`
+	Path        string `json:"path"`
+	Format      string `json:"format"`
+	Description string `json:"description,omitempty"`
+	Shadowed    bool   `json:"shadowed"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Function:     templateInfo
+//
+// Description:  This function gives every copy of every template in the layers, sorted
+//
+//	by name and then in the order they are searched.
+//
+// Inputs:
+//
+//	layers     The places to look
+func templateInfo(layers []templateLayer) []TemplateInfo {
+	var list []TemplateInfo
+	seen := make(map[string]bool)
+	for _, layer := range layers {
+		for _, name := range layer.names() {
+			file := layer.file(name)
+			info := TemplateInfo{
+				Name:     name,
+				Source:   layer.Dir,
+				Path:     layer.path(file),
+				Shadowed: seen[name],
+			}
+			seen[name] = true
+			content, err := fs.ReadFile(layer.Files, file)
+			if err == nil {
+				var tmpl Template
+				tmpl, err = parseTemplate(name, file, string(content))
+				info.Format = tmpl.Meta.Format
+				info.Description = tmpl.Meta.Description
+			}
+			if err != nil {
+				info.Format = "invalid"
+				info.Error = err.Error()
+			}
+			list = append(list, info)
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// Function:     findTemplate
+//
+// Description:  This function reads the first copy of a template found in the layers.
+//
+//	It gives the layer it came from. The layer's file method gives the name
+//	of the file read.
+//
+// Inputs:
+//
+//	layers     The places to look
+//	name       The name of the template
+func findTemplate(layers []templateLayer, name string) ([]byte, templateLayer, error) {
+	for _, layer := range layers {
+		file := layer.file(name)
+		if file == "" {
+			continue
+		}
+		if data, err := fs.ReadFile(layer.Files, file); err == nil {
+			return data, layer, nil
+		}
+	}
+	return nil, templateLayer{}, fmt.Errorf("the template %s doesn't exist", name)
+}
+
+// Function:     copyTemplate
+//
+// Description:  This function copies a template into the user's template directory
+//
+//	under a new name so it can be changed. The name in its header is changed
+//	too. The html file next to a yaml or toml template is copied with it.
+//
+// Inputs:
+//
+//	from       The name of the template to copy
+//	to         The name of the new template
+func copyTemplate(from string, to string) error {
+	data, layer, err := findTemplate(templateLayers(), from)
+	if err != nil {
+		return err
+	}
+	dir := userDir("dialogs")
+	to = filepath.Base(to)
+	if file := (templateLayer{Dir: dir, Files: os.DirFS(dir)}).file(to); file != "" {
+		return fmt.Errorf("the template %s already exists", to)
+	}
+	ext := filepath.Ext(layer.file(from))
+	target := filepath.Join(dir, to+ext)
+
+	//
+	// The header names the template, so it gets the new name.
+	//
+	data, err = renameTemplate(from, layer.file(from), data, to, "")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	if html, err := fs.ReadFile(layer.Files, from+".html"); err == nil && ext != ".json" {
+		if err := writeTemplate(filepath.Join(dir, to+".html"), html); err != nil {
+			return err
+		}
+	}
+	if err := writeTemplate(target, data); err != nil {
+		return err
+	}
+
+	//
+	// A copy is only trusted as much as the template it came from.
+	//
+	return setTemplateTrust([]string{to}, templateTrusted(layer, from))
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// The header a caller gives the trust token in.
+const trustHeader = "X-BB-Token"
+
+// Function:     trustFile
+//
+// Description:  This function gives the file the trust token is kept in. Only the
+//
+//	user can read it, so a caller that gives the token is the user or a
+//	program the user has let read it.
+func trustFile() string {
+	return configPath("token")
+}
+
+// Function:     loadTrustToken
+//
+// Description:  This function reads the trust token, making a new one the first time.
+func loadTrustToken() (string, error) {
+	if data, err := os.ReadFile(trustFile()); err == nil && len(strings.TrimSpace(string(data))) > 0 {
+		return strings.TrimSpace(string(data)), nil
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	if err := os.MkdirAll(filepath.Dir(trustFile()), 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(trustFile(), []byte(token+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Function:     readTrustToken
+//
+// Description:  This function reads the trust token for the cli. It is empty if the
+//
+//	BulletinBoard hasn't made one yet.
+func readTrustToken() string {
+	data, err := os.ReadFile(trustFile())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Function:     isTrusted
+//
+// Description:  This method tells if the caller gave the trust token.
+//
+// Inputs:
+//
+//	given      The token the caller gave
+func (a *App) isTrusted(given string) bool {
+	return a.token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(a.token)) == 1
+}
+
+// Function:     untrustedFile
+//
+// Description:  This function gives the file that lists the user's templates that
+//
+//	aren't trusted yet, like the ones imported from a bundle.
+func untrustedFile() string {
+	return configPath("untrusted.json")
+}
+
+// Function:     loadUntrusted
+//
+// Description:  This function reads the names of the user's templates that aren't
+//
+//	trusted yet.
+func loadUntrusted() map[string]bool {
+	untrusted := make(map[string]bool)
+	data, err := os.ReadFile(untrustedFile())
+	if err != nil {
+		return untrusted
+	}
+	var names []string
+	_ = json.Unmarshal(data, &names)
+	for _, name := range names {
+		untrusted[name] = true
+	}
+	return untrusted
+}
+
+// Function:     setTemplateTrust
+//
+// Description:  This function marks templates of the user as trusted or not. Only
+//
+//	the ones that aren't trusted are kept in the list.
+//
+// Inputs:
+//
+//	names      The names of the templates
+//	trusted    True to trust them
+func setTemplateTrust(names []string, trusted bool) error {
+	untrusted := loadUntrusted()
+	changed := false
+	for _, name := range names {
+		if untrusted[name] == trusted {
+			changed = true
+		}
+		if trusted {
+			delete(untrusted, name)
+		} else {
+			untrusted[name] = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	list := make([]string, 0, len(untrusted))
+	for name := range untrusted {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	if err := os.MkdirAll(filepath.Dir(untrustedFile()), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(list, "", " ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(untrustedFile(), data, 0600)
+}
+
+// Function:     trustTemplate
+//
+// Description:  This function lets one of the user's templates run its scripts.
+//
+// Inputs:
+//
+//	name       The name of the template
+func trustTemplate(name string) error {
+	dir := userDir("dialogs")
+	if (templateLayer{Dir: dir, Files: os.DirFS(dir)}).file(name) == "" {
+		return fmt.Errorf("you don't have a template named %s. Use bb template copy to make one of yours first", name)
+	}
+	return setTemplateTrust([]string{name}, true)
+}
+
+// Function:     templateTrusted
+//
+// Description:  This function tells if a raw template gets the trust token so its
+//
+//	scripts run. The user's own templates are trusted, but imported ones
+//	aren't until the user trusts them. The built-in templates and the ones
+//	installed with the program are trusted. The ones from BB_TEMPLATE_PATH
+//	aren't.
+//
+// Inputs:
+//
+//	layer      The layer the template was found in
+//	name       The name of the template
+func templateTrusted(layer templateLayer, name string) bool {
+	if layer.readOnly() {
+		return true
+	}
+	dir := filepath.Clean(layer.Dir)
+	if dir == filepath.Clean(userDir("dialogs")) {
+		return !loadUntrusted()[name]
+	}
+	for _, installed := range append(installDirs(), dataDirs()...) {
+		if dir == filepath.Clean(filepath.Join(installed, "dialogs")) {
+			return true
+		}
+	}
+	return false
+}
+
+// The policy for the html of raw dialogs from callers that aren't trusted. It keeps
+// the elements a dialog needs and drops scripts and event handlers. Links, images,
+// and style elements are dropped too, since a link would load an outside page into
+// the BulletinBoard and a style element would restyle all of it. Only inline styles
+// that stay inside the element are kept. Buttons return the dialog with
+// data-bb-button and data-bb-cancel in place of scripts.
+var rawDialogPolicy = func() *bluemonday.Policy {
+	policy := bluemonday.NewPolicy()
+	policy.AllowElements("p", "br", "hr", "div", "span", "b", "i", "em", "strong", "u", "s", "small", "sub", "sup",
+		"h1", "h2", "h3", "h4", "h5", "h6", "ul", "ol", "li", "dl", "dt", "dd", "pre", "code", "blockquote",
+		"table", "thead", "tbody", "tfoot", "tr", "th", "td", "caption")
+	policy.AllowElements("form", "fieldset", "legend", "label", "input", "button", "select", "option", "optgroup", "textarea")
+	policy.AllowNoAttrs().OnElements("form", "fieldset", "legend", "label", "button", "select", "option", "optgroup", "textarea")
+	policy.AllowAttrs("class", "id", "title").Globally()
+	policy.AllowAttrs("colspan", "rowspan").OnElements("th", "td")
+	policy.AllowAttrs("type", "name", "value", "placeholder", "checked", "selected", "for", "min", "max", "step",
+		"rows", "cols", "disabled", "readonly", "autofocus", "multiple", "size", "maxlength", "minlength",
+		"pattern", "required").OnElements("form", "label", "input", "button", "select", "option", "textarea")
+	policy.AllowAttrs("data-bb-button", "data-bb-cancel").OnElements("button", "input")
+	policy.AllowStyles("color", "background-color", "font-size", "font-weight", "font-style", "text-align",
+		"text-decoration", "width", "height", "min-width", "max-width", "margin", "margin-top", "margin-bottom",
+		"margin-left", "margin-right", "padding", "padding-top", "padding-bottom", "padding-left", "padding-right",
+		"border", "border-color", "border-radius", "border-style", "border-width", "display", "vertical-align",
+		"white-space").Globally()
+	return policy
+}()
+
+// Function:     sanitizeRawHtml
+//
+// Description:  This function removes the scripts and event handlers from the html of
+//
+//	a raw dialog.
+//
+// Inputs:
+//
+//	html       The html to clean
+func sanitizeRawHtml(html string) string {
+	return rawDialogPolicy.Sanitize(html)
+}
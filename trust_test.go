@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Only the built-in templates and the user's own get the trust token. An imported
+// template has to be trusted first.
+func TestTemplateTrust(t *testing.T) {
+	config := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", config)
+	dir := userDir("dialogs")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"mine", "shared"} {
+		if err := os.WriteFile(filepath.Join(dir, name+".json"), []byte(`{ "html": "<p>hi</p>" }`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	user := templateLayer{Dir: dir, Files: os.DirFS(dir)}
+	other := t.TempDir()
+	path := templateLayer{Dir: other, Files: os.DirFS(other)}
+	builtin := templateLayers()[len(templateLayers())-1]
+
+	if !templateTrusted(user, "mine") || !templateTrusted(builtin, "about") {
+		t.Fatal("the user's own and the built-in templates have to be trusted")
+	}
+	if templateTrusted(path, "mine") {
+		t.Fatal("a template from another directory was trusted")
+	}
+
+	if err := setTemplateTrust([]string{"shared"}, false); err != nil {
+		t.Fatal(err)
+	}
+	if templateTrusted(user, "shared") || !templateTrusted(user, "mine") {
+		t.Fatal("only the imported template should be untrusted")
+	}
+	if err := trustTemplate("shared"); err != nil {
+		t.Fatal(err)
+	}
+	if !templateTrusted(user, "shared") {
+		t.Fatal("the template is still untrusted after trusting it")
+	}
+	if err := trustTemplate("missing"); err == nil {
+		t.Fatal("a template that isn't there was trusted")
+	}
+}
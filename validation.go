@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Function:     compilePattern
+//
+// Description:  This function compiles a field pattern. Like the html pattern
+//
+//	attribute, the pattern has to match the whole value.
+//
+// Inputs:
+//
+//	pattern     The regular expression from the template
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(fmt.Sprintf("^(?:%s)$", pattern))
+}
+
+// Function:     isNumericType
+//
+// Description:  This method tells if the min and max rules compare as numbers.
+func (item DialogItem) isNumericType() bool {
+	return item.ModelType == "number" || item.ModelType == "range"
+}
+
+// Function:     checkRules
+//
+// Description:  This method makes sure the validation rules of an item can be used
+//
+//	before the dialog is shown to the user.
+func (item DialogItem) checkRules() error {
+	if item.Pattern != "" {
+		if _, err := compilePattern(item.Pattern); err != nil {
+			return fmt.Errorf("item %q has a bad pattern: %w", item.Id, err)
+		}
+	}
+	if item.MinLength < 0 || item.MaxLength < 0 {
+		return fmt.Errorf("item %q has a negative length limit", item.Id)
+	}
+	if item.MaxLength > 0 && item.MinLength > item.MaxLength {
+		return fmt.Errorf("item %q has a minLength larger than its maxLength", item.Id)
+	}
+	if item.isNumericType() {
+		for _, limit := range []string{item.Min, item.Max} {
+			if limit == "" {
+				continue
+			}
+			if _, err := strconv.ParseFloat(limit, 64); err != nil {
+				return fmt.Errorf("item %q has a limit, %q, that isn't a number", item.Id, limit)
+			}
+		}
+	}
+	return nil
+}
+
+// Function:     validateValue
+//
+// Description:  This method checks a value the user gave against the rules of the
+//
+//	item. It returns the message to show the user or an empty string.
+//
+// Inputs:
+//
+//	val       The value returned by the frontend
+func (item DialogItem) validateValue(val interface{}) string {
+	var str string
+	switch v := val.(type) {
+	case nil:
+		str = ""
+	case bool:
+		//
+		// A checkbox is only empty when it isn't checked.
+		//
+		if item.Required && !v {
+			return item.ruleMessage("is required")
+		}
+		return ""
+	case string:
+		str = v
+	default:
+		str = fmt.Sprint(v)
+	}
+
+	if str == "" {
+		if item.Required {
+			return item.ruleMessage("is required")
+		}
+		//
+		// The other rules only apply to a value that was given.
+		//
+		return ""
+	}
+	if item.isNumericType() {
+		num, err := strconv.ParseFloat(str, 64)
+		if err != nil || math.IsNaN(num) || math.IsInf(num, 0) {
+			return item.ruleMessage("must be a number")
+		}
+	}
+	if item.Pattern != "" {
+		re, err := compilePattern(item.Pattern)
+		if err != nil || !re.MatchString(str) {
+			return item.ruleMessage("is not in the right format")
+		}
+	}
+	length := utf8.RuneCountInString(str)
+	if item.MinLength > 0 && length < item.MinLength {
+		return item.ruleMessage(fmt.Sprintf("needs at least %d characters", item.MinLength))
+	}
+	if item.MaxLength > 0 && length > item.MaxLength {
+		return item.ruleMessage(fmt.Sprintf("can have at most %d characters", item.MaxLength))
+	}
+	if item.Min != "" && compareLimit(item, str, item.Min) < 0 {
+		return item.ruleMessage(fmt.Sprintf("must be at least %s", item.Min))
+	}
+	if item.Max != "" && compareLimit(item, str, item.Max) > 0 {
+		return item.ruleMessage(fmt.Sprintf("must be at most %s", item.Max))
+	}
+	return ""
+}
+
+// Function:     compareLimit
+//
+// Description:  This function compares a value to a min or max limit. Numbers are
+//
+//	compared as numbers. Everything else, like dates and times, uses formats
+//	that sort as strings.
+//
+// Inputs:
+//
+//	item       The item the value is for
+//	str        The value given
+//	limit      The limit to compare against
+func compareLimit(item DialogItem, str string, limit string) int {
+	if item.isNumericType() {
+		num, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return -1
+		}
+		lim, _ := strconv.ParseFloat(limit, 64)
+		switch {
+		case num < lim:
+			return -1
+		case num > lim:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(str, limit)
+}
+
+// Function:     ruleMessage
+//
+// Description:  This method gives the message for a broken rule. The errorMessage of
+//
+//	the item is used when it is given.
+//
+// Inputs:
+//
+//	problem     What is wrong with the value
+func (item DialogItem) ruleMessage(problem string) string {
+	if item.ErrorMessage != "" {
+		return item.ErrorMessage
+	}
+	label := item.Name
+	if label == "" {
+		label = item.Id
+	}
+	return fmt.Sprintf("%s %s.", label, problem)
+}
+
+// Function:     validateAnswers
+//
+// Description:  This function checks the values returned for a modal dialog. It returns
+//
+//	a map of item ids to the problem with the value. The map is empty when all
+//	of the values are good. Hidden items are not checked.
+//
+// Inputs:
+//
+//	dialog      The modal dialog that was shown
+//	values      The values returned keyed by the item name
+func validateAnswers(dialog ModalDialog, values map[string]interface{}) map[string]string {
+	problems := make(map[string]string)
+	checked := make(map[string]bool)
+	for _, item := range dialog.Items {
+		if item.HtmlType == "" || checked[item.Name] || !dialog.isVisible(item, values) {
+			continue
+		}
+
+		//
+		// Radio buttons share a name, so only the first one is checked.
+		//
+		checked[item.Name] = true
+		if msg := item.validateValue(values[item.Name]); msg != "" {
+			problems[item.Id] = msg
+		}
+	}
+	return problems
+}
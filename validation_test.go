@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+// A number or range item only takes numbers, with or without limits.
+func TestValidateNumbers(t *testing.T) {
+	tests := []struct {
+		item DialogItem
+		val  interface{}
+		want string
+	}{
+		{DialogItem{ModelType: "number", Name: "Count"}, "abc", "Count must be a number."},
+		{DialogItem{ModelType: "number", Name: "Count", Max: "10"}, "abc", "Count must be a number."},
+		{DialogItem{ModelType: "range", Name: "Level", Min: "0"}, "NaN", "Level must be a number."},
+		{DialogItem{ModelType: "number", Name: "Count"}, " 5", "Count must be a number."},
+		{DialogItem{ModelType: "number", Name: "Count", Min: "1", Max: "10"}, "2.5", ""},
+		{DialogItem{ModelType: "number", Name: "Count", Max: "10"}, "11", "Count must be at most 10."},
+		{DialogItem{ModelType: "number", Name: "Count"}, "", ""},
+		{DialogItem{ModelType: "input", Name: "Title"}, "abc", ""},
+	}
+	for _, test := range tests {
+		if got := test.item.validateValue(test.val); got != test.want {
+			t.Errorf("%s %q gives %q, want %q", test.item.ModelType, test.val, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	rt "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Struct:       WizardStep
+//
+// Description:  This tells the frontend where a modal dialog is in a wizard.
+type WizardStep struct {
+	Title string `json:"title"`
+	Page  int    `json:"page"`
+	Pages int    `json:"pages"`
+}
+
+// Struct:       WizardPage
+//
+// Description:  This is one page of a wizard. It is a modal dialog with a title and
+//
+//	an optional condition for skipping the page.
+type WizardPage struct {
+	ModalDialog
+	Title  string     `json:"title,omitempty"`
+	SkipIf *Condition `json:"skipIf,omitempty"`
+}
+
+// Struct:       Wizard
+//
+// Description:  This is a dialog of ordered pages. The values of all of the pages are
+//
+//	returned in one result. The timeout is for each page.
+type Wizard struct {
+	Pages    []WizardPage `json:"pages" binding:"required"`
+	Timeout  int          `json:"timeout,omitempty"`
+	Name     string       `json:"name,omitempty"`
+	Remember bool         `json:"remember,omitempty"`
+}
+
+// Function:     normalizeWizard
+//
+// Description:  This function checks every page of a wizard like a modal dialog.
+//
+// Inputs:
+//
+//	wizard      The wizard to check and update
+func normalizeWizard(wizard *Wizard) error {
+	if len(wizard.Pages) == 0 {
+		return fmt.Errorf("a wizard needs at least one page")
+	}
+	for i := range wizard.Pages {
+		page := &wizard.Pages[i]
+		if err := normalizeModalDialog(&page.ModalDialog); err != nil {
+			return fmt.Errorf("page %d: %w", i+1, err)
+		}
+	}
+
+	//
+	// A page can be skipped on the values of any page.
+	//
+	all := wizard.allItems()
+	for i, page := range wizard.Pages {
+		if page.SkipIf == nil {
+			continue
+		}
+		if err := page.SkipIf.check(); err != nil {
+			return fmt.Errorf("page %d: %w", i+1, err)
+		}
+		if _, ok := all.itemByField(page.SkipIf.Field); !ok {
+			return fmt.Errorf("page %d skips if %q, but there isn't an item with that id", i+1, page.SkipIf.Field)
+		}
+	}
+	return nil
+}
+
+// Function:     allItems
+//
+// Description:  This method gives a modal dialog with the items of every page. It is
+//
+//	used to look up the fields of the skip conditions.
+func (wizard Wizard) allItems() ModalDialog {
+	var all ModalDialog
+	for _, page := range wizard.Pages {
+		all.Items = append(all.Items, page.Items...)
+	}
+	return all
+}
+
+// Function:     nextPage
+//
+// Description:  This method gives the next page to show after the given one. Pages with
+//
+//	a skip condition that matches are passed over. It returns the number of
+//	pages when there aren't any left.
+//
+// Inputs:
+//
+//	from       The page to start after. Use -1 for the first page.
+//	values     The values given so far
+func (wizard Wizard) nextPage(from int, values map[string]interface{}) int {
+	all := wizard.allItems()
+	page := from + 1
+	for ; page < len(wizard.Pages); page++ {
+		if wizard.Pages[page].SkipIf == nil {
+			break
+		}
+		cond := *wizard.Pages[page].SkipIf
+		cond.Field = all.fieldName(cond.Field)
+		if !cond.matches(values) {
+			break
+		}
+	}
+	return page
+}
+
+// Function:     pageDialog
+//
+// Description:  This method makes the modal dialog for a page. The values given so far
+//
+//	are filled in and the Back, Next or Finish, and Cancel buttons are added
+//	if the page doesn't have its own.
+//
+// Inputs:
+//
+//	page       The page to show
+//	step       The number of the page shown to the user
+//	first      True if there isn't a page to go back to
+//	last       True if there isn't a page after this one
+//	values     The values given so far
+func (wizard Wizard) pageDialog(page int, step int, first bool, last bool, values map[string]interface{}) ModalDialog {
+	dialog := wizard.Pages[page].ModalDialog
+	dialog.Timeout = wizard.Timeout
+	dialog.Step = &WizardStep{
+		Title: wizard.Pages[page].Title,
+		Page:  step,
+		Pages: len(wizard.Pages),
+	}
+	dialog.Items = append([]DialogItem(nil), dialog.Items...)
+	for i, item := range dialog.Items {
+		if val, ok := values[item.Name]; ok && val != nil && item.HtmlType != "" {
+			dialog.Items[i].fill(val)
+		}
+	}
+
+	hasAction := func(actions ...string) bool {
+		for _, b := range dialog.Buttons {
+			for _, action := range actions {
+				if b.Action == action {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	var buttons []DialogButton
+	if !first && !hasAction("back") {
+		buttons = append(buttons, DialogButton{Name: "Back", Id: "back", Action: "back"})
+	}
+	if !hasAction("next", "submit") {
+		if last {
+			buttons = append(buttons, DialogButton{Name: "Finish", Id: "finish", Action: "submit"})
+		} else {
+			buttons = append(buttons, DialogButton{Name: "Next", Id: "next", Action: "next"})
+		}
+	}
+	buttons = append(buttons, dialog.Buttons...)
+	if !hasAction("cancel") {
+		buttons = append(buttons, DialogButton{Name: "Cancel", Id: "cancel", Action: "cancel"})
+	}
+	dialog.Buttons = buttons
+	return dialog
+}
+
+// Function:     runWizard
+//
+// Description:  This function shows the pages of a wizard in order and collects the
+//
+//	values from all of them into one result. The window stays up between pages.
+//
+// Inputs:
+//
+//	ctx        The Wails runtime context
+//	done       Closed when the caller has gone away
+//	wizard     The wizard to show
+func runWizard(ctx context.Context, done <-chan struct{}, wizard Wizard) DialogResult {
+	remembered := make(map[string]interface{})
+	if wizard.Remember {
+		remembered = loadAnswers(wizard.Name)
+	}
+	result := wizard.walk(remembered, func(dialog ModalDialog) DialogResult {
+		return runModal(ctx, done, dialog)
+	})
+
+	//
+	// Take the last page down. If the caller went away, it is down already.
+	//
+	select {
+	case <-done:
+	default:
+		rt.EventsEmit(ctx, "dialogclose")
+	}
+	if wizard.Remember && result.Status == StatusSubmitted && len(result.Values) > 0 {
+		if err := saveAnswers(wizard.Name, wizard.allItems().Items, result.Values); err != nil {
+			log.Printf("Unable to keep the answers for %s: %v", wizard.Name, err)
+		}
+	}
+	return result
+}
+
+// Function:     walk
+//
+// Description:  This method takes the user through the pages of a wizard. Each page
+//
+//	keeps its own answers, so going back and changing a page replaces them.
+//	The result only has the answers of the pages the user went through to
+//	the end, and only for the items those pages show.
+//
+// Inputs:
+//
+//	remembered The answers kept from the last time
+//	show       Shows a page and waits for the user to answer it
+func (wizard Wizard) walk(remembered map[string]interface{}, show func(ModalDialog) DialogResult) DialogResult {
+	answers := make(map[int]map[string]interface{})
+	var shown []int
+	page := wizard.nextPage(-1, wizard.visitedValues(shown, answers))
+	result := DialogResult{Status: StatusSubmitted}
+	for page < len(wizard.Pages) {
+		//
+		// A later page's skipIf can hang on the answers to this page, so any later
+		// page is taken as one that could be shown. Finish is only on the last one.
+		//
+		last := page == len(wizard.Pages)-1
+		//
+		// The pages show the values given so far, or the remembered ones. A page
+		// that is skipped now still shows what was given on it before.
+		//
+		shownValues := make(map[string]interface{}, len(remembered))
+		for key, val := range remembered {
+			shownValues[key] = val
+		}
+		for _, values := range answers {
+			for key, val := range values {
+				shownValues[key] = val
+			}
+		}
+		dialog := wizard.pageDialog(page, len(shown)+1, len(shown) == 0, last, shownValues)
+		pageResult := show(dialog)
+		result.Button = pageResult.Button
+		result.Action = pageResult.Action
+		if pageResult.Status != StatusSubmitted {
+			result.Status = pageResult.Status
+			break
+		}
+		if dialog.button(pageResult.Button).submitsValues() {
+			answers[page] = pageResult.Values
+		}
+
+		switch pageResult.Action {
+		case "back":
+			if len(shown) > 0 {
+				page = shown[len(shown)-1]
+				shown = shown[:len(shown)-1]
+			}
+			continue
+
+		case "next", "submit":
+			shown = append(shown, page)
+			page = wizard.nextPage(page, wizard.visitedValues(shown, answers))
+			continue
+		}
+
+		//
+		// Any other action ends the wizard with what has been given so far.
+		//
+		shown = append(shown, page)
+		break
+	}
+	result.Values = wizard.visitedValues(shown, answers)
+	return result
+}
+
+// Function:     visitedValues
+//
+// Description:  This method puts together the answers of the pages in the order they
+//
+//	were shown. Values of items hidden by a showIf are left out.
+//
+// Inputs:
+//
+//	shown      The pages the user went through
+//	answers    The answers of each page
+func (wizard Wizard) visitedValues(shown []int, answers map[int]map[string]interface{}) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, page := range shown {
+		for key, val := range wizard.Pages[page].visibleValues(answers[page]) {
+			values[key] = val
+		}
+	}
+	return values
+}
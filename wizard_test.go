@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Going back and changing an answer so a page is skipped drops the answers given on
+// that page, and a hidden item's old value is dropped too.
+func TestWizardBackThenSkip(t *testing.T) {
+	wizard := Wizard{Pages: []WizardPage{
+		{ModalDialog: ModalDialog{Items: []DialogItem{
+			{ModelType: "input", HtmlType: "text", Name: "kind", Id: "kind"},
+			{ModelType: "checkbox", HtmlType: "checkbox", Name: "extra", Id: "extra"},
+			{ModelType: "input", HtmlType: "text", Name: "detail", Id: "detail", ShowIf: &Condition{Field: "extra", Value: "true"}},
+		}}},
+		{SkipIf: &Condition{Field: "kind", Value: "simple"}, ModalDialog: ModalDialog{Items: []DialogItem{
+			{ModelType: "input", HtmlType: "text", Name: "size", Id: "size"},
+		}}},
+		{ModalDialog: ModalDialog{Items: []DialogItem{
+			{ModelType: "input", HtmlType: "text", Name: "done", Id: "done"},
+		}}},
+	}}
+
+	answers := []DialogResult{
+		{Status: StatusSubmitted, Button: "next", Action: "next", Values: map[string]interface{}{"kind": "full", "extra": true, "detail": "more"}},
+		{Status: StatusSubmitted, Button: "next", Action: "next", Values: map[string]interface{}{"size": "10"}},
+		{Status: StatusSubmitted, Button: "back", Action: "back", Values: map[string]interface{}{}},
+		{Status: StatusSubmitted, Button: "back", Action: "back", Values: map[string]interface{}{}},
+		{Status: StatusSubmitted, Button: "next", Action: "next", Values: map[string]interface{}{"kind": "simple", "extra": false, "detail": "more"}},
+		{Status: StatusSubmitted, Button: "finish", Action: "submit", Values: map[string]interface{}{"done": "yes"}},
+	}
+	var steps []int
+	result := wizard.walk(nil, func(dialog ModalDialog) DialogResult {
+		if len(steps) == len(answers) {
+			t.Fatal("too many pages were shown")
+		}
+		steps = append(steps, dialog.Step.Page)
+		return answers[len(steps)-1]
+	})
+
+	if want := []int{1, 2, 3, 2, 1, 2}; !reflect.DeepEqual(steps, want) {
+		t.Fatalf("the steps shown are %v, want %v", steps, want)
+	}
+	if result.Status != StatusSubmitted {
+		t.Fatalf("the status is %v", result.Status)
+	}
+	want := map[string]interface{}{"kind": "simple", "extra": false, "done": "yes"}
+	if !reflect.DeepEqual(result.Values, want) {
+		t.Fatalf("the values are %v, want %v", result.Values, want)
+	}
+}